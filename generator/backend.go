@@ -0,0 +1,101 @@
+package generator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ImageBackend represents the set of image operations that fotoDen's generator
+// uses in order to read, resize, and convert images.
+//
+// fotoDen includes two backends: vips, which uses libvips via bimg, and go,
+// which is written in pure Go. vips is the default, unless fotoDen is built
+// with the purego build tag, in which case only the go backend is available.
+// The backend can also be selected with the ImageBackend field in Config.
+//
+// Formats are always given by name (e.g., jpeg, png, webp, avif).
+type ImageBackend interface {
+	// Format returns the name of the format of an image,
+	// or an empty string if the image could not be identified.
+	Format(image []byte) string
+
+	// Size returns the width and height of an image, in pixels.
+	Size(image []byte) (int, int, error)
+
+	// Resize resizes an image to the given width and height, and encodes
+	// it into the given format. If quality is zero, the default quality
+	// of the backend is used.
+	Resize(image []byte, width int, height int, format string, quality int) ([]byte, error)
+
+	// CanSave returns true if the backend can encode images into the given format.
+	CanSave(format string) bool
+}
+
+var (
+	imageBackends       = make(map[string]ImageBackend)
+	defaultImageBackend string
+)
+
+// registerImageBackend registers an image backend under a name.
+// If def is true, or if no backend has been registered yet,
+// the backend also becomes the default backend.
+func registerImageBackend(name string, backend ImageBackend, def bool) {
+	imageBackends[name] = backend
+	if def || defaultImageBackend == "" {
+		defaultImageBackend = name
+	}
+}
+
+// ImageBackends returns the names of all image backends available in this build of fotoDen.
+func ImageBackends() []string {
+	b := make([]string, 0, len(imageBackends))
+	for k := range imageBackends {
+		b = append(b, k)
+	}
+
+	return b
+}
+
+// currentImageBackend is the name of the image backend set by SetImageBackend.
+var currentImageBackend string
+
+// SetImageBackend sets the image backend used by every image operation in the generator.
+// An empty name sets the default image backend for this build of fotoDen.
+// Returns an error if the backend is not available.
+//
+// The image backend is shared by the whole process, as it usually depends on
+// the libraries that fotoDen was built with, rather than on a specific site.
+func SetImageBackend(name string) error {
+	name = strings.ToLower(name)
+	if name == "" {
+		name = defaultImageBackend
+	}
+
+	if _, ok := imageBackends[name]; !ok {
+		return fmt.Errorf("image backend %s is not available in this build of fotoDen (available: %v)", name, ImageBackends())
+	}
+
+	currentImageBackend = name
+	return nil
+}
+
+// CurrentImageBackend returns the image backend set by SetImageBackend.
+// If SetImageBackend was never called, the backend in CurrentConfig is used,
+// or the default image backend if CurrentConfig does not set one.
+func CurrentImageBackend() (ImageBackend, error) {
+	name := currentImageBackend
+	if name == "" {
+		name = strings.ToLower(CurrentConfig.ImageBackend)
+	}
+
+	if name == "" {
+		name = defaultImageBackend
+	}
+
+	b, ok := imageBackends[name]
+	if !ok {
+		return nil, fmt.Errorf("image backend %s is not available in this build of fotoDen (available: %v)", name, ImageBackends())
+	}
+
+	return b, nil
+}
@@ -1,42 +1,53 @@
 package generator
 
 import (
-	"fmt"
-	"os"
 	"path/filepath"
-	"strings"
-
-	"github.com/h2non/bimg"
 )
 
-// BatchOperationOnFiles takes two arguments, an array of file names, and a function that takes a string and an int.
+// BatchOperationOnFiles takes an operation name, an array of file names, and a function that takes a string and an int.
 //
 // The string will be the file name, while the int will be the index of the file in the array.
 //
-// The function will iterate over every file name until the end of the array is reached,
-// passing the file name into the function.
+// The function will be called for every file name in the array, passing the file name into the function.
+// Files are operated on concurrently in the shared WorkerPool (see Workers), so the function must be safe
+// to call from several goroutines at once. Returns once every file has been operated on.
+//
+// If the function returns an error for any file, the operation continues with the rest of the files.
+// Every error is recorded as a FileError with the operation name, and returned together as a BatchError,
+// in the same order as the array of files. Otherwise, nil is returned.
 //
 // Also takes an int channel - it will output '1' on that channel for every file operated on.
-func BatchOperationOnFiles(files []string, fn func(string, int) error, ch chan int) error {
-	for i := 0; i < len(files); i++ {
+func BatchOperationOnFiles(op string, files []string, fn func(string, int) error, ch chan int) error {
+	errs := make([]error, len(files))
+
+	Workers().Run(len(files), func(i int) {
 		ch <- 1
-		err := fn(files[i], i)
+		errs[i] = fn(files[i], i)
+	})
+
+	var batchErr BatchError
+	for i, err := range errs {
 		if err != nil {
-			fmt.Println("An error occurred during operation: ", err)
+			verbose("An error occurred during operation " + op + " on " + files[i] + ": " + err.Error())
+			batchErr = append(batchErr, &FileError{
+				File: files[i],
+				Op:   op,
+				Err:  err,
+			})
 		}
 	}
 
-	return nil
+	return batchErr.ErrorOrNil()
 }
 
-// BatchCopyFile copies a list of file string names to the current WorkingDirectory by index.
-// Returns an error if one occurs, otherwise nil.
+// BatchCopyFile copies a list of file names in the source directory to the given directory, within an FS.
+// If source is an empty string, the file names are relative to the current working directory.
+// Returns a BatchError if any files failed to copy, otherwise nil.
 // Also preserves the current extension of the file. (This is due to a NeoCities Free restriction)
-func BatchCopyFile(files []string, directory string, ch chan int) error {
-	wd, _ := os.Getwd()
-	verbose("Attempting a batch copy from " + wd + " to " + directory)
+func BatchCopyFile(fsys FS, files []string, source string, directory string, ch chan int) error {
+	verbose("Attempting a batch copy from " + source + " to " + directory)
 	batchCopyFile := func(file string, index int) error {
-		err := CopyFile(file, filepath.Join(directory, file))
+		err := CopyFile(fsys, filepath.Join(source, file), filepath.Join(directory, file))
 		if err != nil {
 			return err
 		}
@@ -44,7 +55,7 @@ func BatchCopyFile(files []string, directory string, ch chan int) error {
 		return nil
 	}
 
-	err := BatchOperationOnFiles(files, batchCopyFile, ch)
+	err := BatchOperationOnFiles("copy", files, batchCopyFile, ch)
 	if err != nil {
 		return err
 	}
@@ -52,38 +63,37 @@ func BatchCopyFile(files []string, directory string, ch chan int) error {
 	return nil
 }
 
-// BatchImageConversion resizes a set of images to thumbnail size and puts them into the given directory, as according to CurrentConfig.
-// Returns an error if one occurs, otherwise nil.
-func BatchImageConversion(files []string, prefix string, directory string, ScalingOptions ImageScale, ch chan int) error {
-	wd, _ := os.Getwd()
-	verbose("Generating thumbnails in " + wd + " and placing them in " + directory)
+// BatchImageConversion resizes a set of images in the source directory to thumbnail size and puts them into the given directory,
+// within an FS. If source is an empty string, the file names are relative to the current working directory.
+// Every image is generated as a JPEG, as well as in any extra formats given in ScalingOptions.
+// Returns a BatchError if any images failed to resize, otherwise nil.
+func BatchImageConversion(fsys FS, files []string, source string, prefix string, directory string, ScalingOptions ImageScale, ch chan int) error {
+	verbose("Generating thumbnails from " + source + " and placing them in " + directory)
 	batchResizeImage := func(file string, index int) error {
-		err := ResizeImage(file, prefix+"_"+strings.Split(filepath.Base(file), ".")[0]+".jpg", ScalingOptions, directory, bimg.JPEG)
-		if err != nil && err != fmt.Errorf("skip") {
+		err := GenerateImageSize(fsys, filepath.Join(source, file), prefix, ScalingOptions, directory)
+		if err != nil {
 			return err
 		}
 
 		return nil
 	}
 
-	err := BatchOperationOnFiles(files, batchResizeImage, ch)
+	err := BatchOperationOnFiles("resize ("+prefix+")", files, batchResizeImage, ch)
 	if err != nil {
 		return err
-
 	}
 
 	return nil
 }
 
-// BatchImageMeta takes a string array of files, and a destination directory, and generates a JSON file
-// containing non-EXIF metadata (such as names and descriptions) of image files for fotoDen to process.
-func BatchImageMeta(files []string, directory string, ch chan int) error {
-	wd, _ := os.Getwd()
-	verbose("Writing image metadata templates from images in " + wd + "and placing them in " + directory)
+// BatchImageMeta takes an FS, a string array of files in the source directory, and a destination directory, and generates a JSON file
+// containing metadata (such as names and descriptions, as well as EXIF information) of image files for fotoDen to process.
+// If source is an empty string, the file names are relative to the current working directory.
+// Returns a BatchError if metadata failed to generate for any images, otherwise nil.
+func BatchImageMeta(fsys FS, files []string, source string, directory string, ch chan int) error {
+	verbose("Writing image metadata from images in " + source + " and placing them in " + directory)
 	batchImageMeta := func(file string, index int) error {
-		meta := new(ImageMeta)
-
-		err := meta.WriteImageMeta(directory, file)
+		err := GenerateImageMeta(fsys, filepath.Join(source, file), directory)
 		if err != nil {
 			return err
 		}
@@ -91,7 +101,7 @@ func BatchImageMeta(files []string, directory string, ch chan int) error {
 		return nil
 	}
 
-	err := BatchOperationOnFiles(files, batchImageMeta, ch)
+	err := BatchOperationOnFiles("meta", files, batchImageMeta, ch)
 	if err != nil {
 		return err
 	}
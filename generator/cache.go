@@ -0,0 +1,122 @@
+package generator
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+)
+
+// Cache represents an itemsCache.json file used by fotoDen's generator.
+// It records the hash of every source image in an album, as well as the
+// ImageScale that every size of that image was last generated with,
+// so that albums can be regenerated without generating every image again.
+//
+// This is only used by the generator - fotoDen.js never reads this.
+type Cache struct {
+	Source string                  `json:"source,omitempty"` // The directory the album was last generated or synced from.
+	Images map[string]*CachedImage `json:"images"`           // Every source image in the album, by name.
+}
+
+// CachedImage represents a single source image in a Cache.
+type CachedImage struct {
+	Hash  string                `json:"hash"`  // The SHA-256 hash of the source image.
+	Sizes map[string]ImageScale `json:"sizes"` // The sizes generated from the source, and the ImageScale used.
+}
+
+// NewCache creates a new, empty Cache.
+func NewCache() *Cache {
+	return &Cache{Images: make(map[string]*CachedImage)}
+}
+
+// ReadCache is a method for reading a cache from a file in an FS.
+// Returns an error if any occur.
+func (cache *Cache) ReadCache(fsys FS, filePath string) error {
+	verbose("Reading cache from " + filePath)
+	err := ReadJSON(fsys, filePath, cache)
+	if err != nil {
+		return err
+	}
+
+	if cache.Images == nil {
+		cache.Images = make(map[string]*CachedImage)
+	}
+
+	return nil
+}
+
+// WriteCache is a method for writing a cache to a file in an FS.
+// Returns an error if any occur.
+func (cache *Cache) WriteCache(fsys FS, filePath string) error {
+	verbose("Writing cache to " + filePath)
+	err := WriteJSON(fsys, filePath, "multi", cache)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// HashFile returns the SHA-256 hash of a file in an FS, as a hexadecimal string.
+func HashFile(fsys FS, file string) (string, error) {
+	f, err := fsys.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%x", sha256.Sum256(f)), nil
+}
+
+// SourceChanged returns true if the named image is not in the cache,
+// or if its hash does not match the cached hash.
+func (cache *Cache) SourceChanged(name string, hash string) bool {
+	i, ok := cache.Images[name]
+	return !ok || i.Hash != hash
+}
+
+// SizeChanged returns true if the named size of an image needs to be generated again -
+// either because the source has changed, or because the size was never generated,
+// or because the size was generated with a different ImageScale.
+func (cache *Cache) SizeChanged(name string, hash string, size string, scale ImageScale) bool {
+	if cache.SourceChanged(name, hash) {
+		return true
+	}
+
+	s, ok := cache.Images[name].Sizes[size]
+	return !ok || !reflect.DeepEqual(s, scale)
+}
+
+// SetImage records the hash of an image into the cache.
+// If the hash differs from the one already cached, the sizes recorded for the image are cleared.
+func (cache *Cache) SetImage(name string, hash string) {
+	if cache.SourceChanged(name, hash) {
+		cache.Images[name] = &CachedImage{
+			Hash:  hash,
+			Sizes: make(map[string]ImageScale),
+		}
+	}
+}
+
+// SetSize records the ImageScale that a size of an image was generated with.
+// The image must have been recorded with SetImage beforehand.
+func (cache *Cache) SetSize(name string, size string, scale ImageScale) {
+	if i, ok := cache.Images[name]; ok {
+		i.Sizes[size] = scale
+	}
+}
+
+// Stale returns the names of every cached image that is not in the given array of names.
+func (cache *Cache) Stale(names []string) []string {
+	current := make(map[string]bool)
+	for _, n := range names {
+		current[n] = true
+	}
+
+	stale := make([]string, 0)
+	for n := range cache.Images {
+		if !current[n] {
+			stale = append(stale, n)
+		}
+	}
+
+	return stale
+}
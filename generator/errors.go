@@ -0,0 +1,88 @@
+package generator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// FileError represents an error that occurred during an operation on a single file.
+type FileError struct {
+	File string // The file that was operated on.
+	Op   string // The operation that failed (e.g., copy, resize (small), meta).
+	Err  error  // The error that occurred.
+}
+
+func (e *FileError) Error() string {
+	if e.File == "" {
+		return e.Err.Error()
+	}
+
+	return e.Op + " " + e.File + ": " + e.Err.Error()
+}
+
+func (e *FileError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError represents every FileError that occurred during one or more batch operations.
+//
+// A batch operation does not stop when a single file fails - instead, every file that failed
+// is recorded in a BatchError, which is returned once the operation is finished.
+type BatchError []*FileError
+
+func (e BatchError) Error() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%d file operation(s) failed:", len(e))
+	for _, f := range e {
+		b.WriteString("\n\t" + f.Error())
+	}
+
+	return b.String()
+}
+
+// Append appends an error to a BatchError, and returns the resulting BatchError.
+//
+// If the error is a BatchError or a FileError, its FileErrors are appended as they are.
+// Any other error is appended as a FileError without a file. A nil error is ignored.
+func (e BatchError) Append(err error) BatchError {
+	if err == nil {
+		return e
+	}
+
+	var b BatchError
+	var f *FileError
+	switch {
+	case errors.As(err, &b):
+		return append(e, b...)
+	case errors.As(err, &f):
+		return append(e, f)
+	default:
+		return append(e, &FileError{Err: err})
+	}
+}
+
+// Files returns the name of every file in a BatchError, without duplicates.
+func (e BatchError) Files() []string {
+	seen := make(map[string]bool)
+	files := make([]string, 0)
+
+	for _, f := range e {
+		if f.File != "" && !seen[f.File] {
+			seen[f.File] = true
+			files = append(files, f.File)
+		}
+	}
+
+	return files
+}
+
+// ErrorOrNil returns the BatchError as an error if it contains any errors, or nil otherwise.
+func (e BatchError) ErrorOrNil() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
@@ -0,0 +1,141 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/rwcarlsen/goexif/exif"
+)
+
+// ImageExif represents the EXIF information of an image, as read during generation.
+// This is stored in an image's ImageMeta, so that fotoDen can display this information
+// without having to download the source image on the web frontend.
+//
+// Any field that could not be read from the image is left blank.
+type ImageExif struct {
+	Make         string  // The make of the camera.
+	Model        string  // The model of the camera.
+	Lens         string  // The model of the lens.
+	FocalLength  float64 // The focal length of the lens, in millimeters.
+	Aperture     float64 // The f-number of the lens.
+	ShutterSpeed string  // The exposure time, in seconds (e.g., 1/250).
+	ISO          int     // The ISO speed rating.
+	DateTaken    string  // The date the image was captured, in the form 2006-01-02T15:04:05.
+	Width        int     // The width of the image, in pixels.
+	Height       int     // The height of the image, in pixels.
+}
+
+// ReadImageExif reads the EXIF information of an image file in an FS into an ImageExif struct.
+//
+// The dimensions of the image are always read, even if the image has no EXIF
+// information - in that case, an ImageExif struct is still returned, alongside the error.
+func ReadImageExif(fsys FS, file string) (*ImageExif, error) {
+	verbose("Reading EXIF information from " + file)
+	e := new(ImageExif)
+
+	backend, err := CurrentImageBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	image, err := fsys.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	e.Width, e.Height, err = backend.Size(image)
+	if err != nil {
+		return nil, err
+	}
+
+	x, err := exif.Decode(bytes.NewReader(image))
+	if err != nil {
+		return e, fmt.Errorf("ReadImageExif: could not decode EXIF from %s: %v", file, err)
+	}
+
+	e.Make = exifString(x, exif.Make)
+	e.Model = exifString(x, exif.Model)
+	e.Lens = exifString(x, exif.LensModel)
+	e.FocalLength = exifFloat(x, exif.FocalLength)
+	e.Aperture = exifFloat(x, exif.FNumber)
+	e.ISO = exifInt(x, exif.ISOSpeedRatings)
+
+	if r := exifRat(x, exif.ExposureTime); r != nil {
+		if r.IsInt() {
+			e.ShutterSpeed = r.Num().String()
+		} else {
+			e.ShutterSpeed = r.String()
+		}
+	}
+
+	d := exifString(x, exif.DateTimeOriginal)
+	if d == "" {
+		d = exifString(x, exif.DateTime)
+	}
+	if t, err := time.Parse("2006:01:02 15:04:05", d); err == nil {
+		e.DateTaken = t.Format("2006-01-02T15:04:05")
+	}
+
+	// orientations 5 through 8 are rotated by 90 degrees,
+	// so the displayed width and height are swapped
+	if o := exifInt(x, exif.Orientation); o >= 5 && o <= 8 {
+		e.Width, e.Height = e.Height, e.Width
+	}
+
+	return e, nil
+}
+
+func exifString(x *exif.Exif, n exif.FieldName) string {
+	t, err := x.Get(n)
+	if err != nil {
+		return ""
+	}
+
+	s, err := t.StringVal()
+	if err != nil {
+		return ""
+	}
+
+	return strings.TrimSpace(strings.TrimRight(s, "\x00"))
+}
+
+func exifRat(x *exif.Exif, n exif.FieldName) *big.Rat {
+	t, err := x.Get(n)
+	if err != nil {
+		return nil
+	}
+
+	r, err := t.Rat(0)
+	if err != nil {
+		return nil
+	}
+
+	return r
+}
+
+func exifFloat(x *exif.Exif, n exif.FieldName) float64 {
+	r := exifRat(x, n)
+	if r == nil {
+		return 0
+	}
+
+	f, _ := r.Float64()
+	return f
+}
+
+func exifInt(x *exif.Exif, n exif.FieldName) int {
+	t, err := x.Get(n)
+	if err != nil {
+		return 0
+	}
+
+	i, err := t.Int(0)
+	if err != nil {
+		return 0
+	}
+
+	return i
+}
@@ -1,9 +1,7 @@
 package generator
 
 import (
-	"io/ioutil"
 	"os"
-	"path/filepath"
 )
 
 // GetArrayOfFilesAndFolders takes an array of os.FileInfo (usually from from os.Readdir()), and returns a string array of all non-directories.
@@ -35,25 +33,18 @@ func GetArrayOfFolders(directory []os.FileInfo) []string {
 	return folderArray
 }
 
-// CopyFile takes three arguments - the name of the file, the name of the new file, and the destination.
-// This assumes the file we're renaming is in the current working directory, or it is reachable
-// via the current working directory.
+// CopyFile takes three arguments - an FS, the name of the file, and the name of the new file,
+// and copies the file within the FS. Relative names are relative to the current working directory.
 //
 // Returns an error if one occurs - otherwise returns nil.
-func CopyFile(file string, dest string) error {
-	verbose("Copying " + file + " to " + filepath.Join(dest))
-	fileReader, err := ioutil.ReadFile(file)
+func CopyFile(fsys FS, file string, dest string) error {
+	verbose("Copying " + file + " to " + dest)
+	fileReader, err := fsys.ReadFile(file)
 	if err != nil {
 		return err
 	}
 
-	toWrite, err := os.Create(filepath.Join(dest))
-	defer toWrite.Close()
-	if err != nil {
-		return err
-	}
-
-	_, err = toWrite.Write(fileReader)
+	err = fsys.WriteFile(dest, fileReader, 0644)
 	if err != nil {
 		return err
 	}
@@ -1,9 +1,7 @@
 package generator
 
 import (
-	"io/ioutil"
-	"os"
-	"path"
+	"path/filepath"
 )
 
 // Folder represents a folderInfo.json file used by fotoDen.
@@ -29,7 +27,7 @@ type Album struct {
 
 // GenerateFolderInfo generates a Folder object that can be used for folder configuration.
 // If directory is an empty string, it does it in the current directory.
-// Otherwise, it attempts to reach the directory from the current working directory.
+// Otherwise, the directory can be either absolute, or relative to the current working directory.
 //
 // If name is an empty string, it uses the target directory's name.
 //
@@ -40,35 +38,30 @@ type Album struct {
 func GenerateFolderInfo(directory string, name string) (*Folder, error) {
 	folder := new(Folder)
 
-	if directory != "" {
-		wd, _ := os.Getwd()
-		defer os.Chdir(wd)
-		err := os.Chdir(directory)
-		if err != nil {
-			return folder, err
-		}
+	currentDirectory, err := filepath.Abs(directory)
+	if err != nil {
+		return folder, err
 	}
 
-	currentDirectory, _ := os.Getwd()
 	verbose("Generating folder info from: " + currentDirectory)
 
 	if name == "" {
-		folder.Name = path.Base(currentDirectory)
+		folder.Name = filepath.Base(currentDirectory)
 	} else {
 		folder.Name = name
 	}
 
-	folder.ShortName = path.Base(currentDirectory)
+	folder.ShortName = filepath.Base(currentDirectory)
 	folder.Subfolders = []string{}
 
 	return folder, nil
 }
 
-// ReadFolderInfo is a method for reading folder info from a file.
+// ReadFolderInfo is a method for reading folder info from a file in an FS.
 // Returns an error if any occur.
-func (folder *Folder) ReadFolderInfo(filePath string) error {
+func (folder *Folder) ReadFolderInfo(fsys FS, filePath string) error {
 	verbose("Reading folder infomation from " + filePath)
-	err := ReadJSON(filePath, folder)
+	err := ReadJSON(fsys, filePath, folder)
 	if err != nil {
 		return err
 	}
@@ -76,11 +69,11 @@ func (folder *Folder) ReadFolderInfo(filePath string) error {
 	return nil
 }
 
-// WriteFolderInfo is a method for writing fotoDen folder info to a file.
+// WriteFolderInfo is a method for writing fotoDen folder info to a file in an FS.
 // Returns an error if any occur.
-func (folder *Folder) WriteFolderInfo(filePath string) error {
+func (folder *Folder) WriteFolderInfo(fsys FS, filePath string) error {
 	verbose("Writing folder (" + folder.ShortName + ") to " + filePath)
-	err := WriteJSON(filePath, "multi", folder)
+	err := WriteJSON(fsys, filePath, "multi", folder)
 	if err != nil {
 		return err
 	}
@@ -97,35 +90,27 @@ type Items struct {
 	ItemsInFolder []string `json:"items"` // All the items in a folder, by name, in an array.
 }
 
-// GenerateItemInfo generates an Items object based on the contents of the directory.
+// GenerateItemInfo generates an Items object based on the contents of the directory in an FS.
 // This automatically strips non-images.
-func GenerateItemInfo(directory string) (*Items, error) {
+func GenerateItemInfo(fsys FS, directory string) (*Items, error) {
 	items := new(Items)
 
 	verbose("Reading items in folder: " + directory)
 
-	dir, err := os.Open(directory)
-	defer dir.Close()
-	if err != nil {
-		return items, err
-	}
-
-	dirContents, err := dir.Readdir(0)
+	dirContents, err := fsys.ReadDir(directory)
 	if err != nil {
 		return items, err
 	}
-	defer os.Chdir(WorkingDirectory)
-	os.Chdir(directory)
-	items.ItemsInFolder = IsolateImages(GetArrayOfFiles(dirContents))
+	items.ItemsInFolder = IsolateImages(fsys, directory, GetArrayOfFiles(dirContents))
 
 	return items, nil
 }
 
-// ReadItemsInfo is a method for reading items info from a file.
+// ReadItemsInfo is a method for reading items info from a file in an FS.
 // Returns an error if any occur.
-func (items *Items) ReadItemsInfo(filePath string) error {
+func (items *Items) ReadItemsInfo(fsys FS, filePath string) error {
 	verbose("Reading items infomation from " + filePath)
-	err := ReadJSON(filePath, items)
+	err := ReadJSON(fsys, filePath, items)
 	if err != nil {
 		return err
 	}
@@ -133,11 +118,11 @@ func (items *Items) ReadItemsInfo(filePath string) error {
 	return nil
 }
 
-// WriteItemsInfo is a method for writing items info to a file.
+// WriteItemsInfo is a method for writing items info to a file in an FS.
 // Returns an error if any occur.
-func (items *Items) WriteItemsInfo(filePath string) error {
+func (items *Items) WriteItemsInfo(fsys FS, filePath string) error {
 	verbose("Writing items to " + filePath)
-	err := WriteJSON(filePath, "single", items)
+	err := WriteJSON(fsys, filePath, "single", items)
 	if err != nil {
 		return err
 	}
@@ -151,25 +136,36 @@ func (items *Items) WriteItemsInfo(filePath string) error {
 // UpdateSubdirectories updates a Folder object's subdirectories according to the given directory.
 // If directory is an empty string, will attempt to update the Folder from the current working directory.
 // Returns an error, if any occurs, otherwise the number of directories and a nil error.
+//
+// Deprecated: UpdateSubdirectories uses the image root directory in CurrentConfig.
+// Use UpdateSubdirectoriesExcept instead.
 func (folder *Folder) UpdateSubdirectories(directory string) (int, error) {
-	var currentDirectory string
-	switch directory {
-	case "":
-		currentDirectory, _ = os.Getwd()
-	default:
-		currentDirectory = directory
+	return folder.UpdateSubdirectoriesExcept(OSFS{}, directory, CurrentConfig.ImageRootDirectory)
+}
+
+// UpdateSubdirectoriesExcept updates a Folder object's subdirectories according to the given directory in an FS,
+// leaving out any of the given directory names (usually, the image root directory of an album).
+// If directory is an empty string, will attempt to update the Folder from the current working directory.
+// Returns an error, if any occurs, otherwise the number of directories and a nil error.
+func (folder *Folder) UpdateSubdirectoriesExcept(fsys FS, directory string, exclude ...string) (int, error) {
+	currentDirectory := directory
+	if currentDirectory == "" {
+		currentDirectory = "."
 	}
 
 	verbose("Updating subdirectories in " + directory)
 
-	fileArray, err := ioutil.ReadDir(currentDirectory)
+	fileArray, err := fsys.ReadDir(currentDirectory)
 	if err != nil {
 		return 0, err
 	}
 
-	// special cases for the root css/js directories, and the album image location
-	folder.Subfolders = RemoveItemFromStringArray(GetArrayOfFolders(fileArray), CurrentConfig.ImageRootDirectory)
+	folder.Subfolders = GetArrayOfFolders(fileArray)
+	for _, e := range exclude {
+		folder.Subfolders = RemoveItemFromStringArray(folder.Subfolders, e)
+	}
 
+	// special cases for the root css/js directories
 	// really lazy, find a better way to do this
 	folder.Subfolders = RemoveItemFromStringArray(folder.Subfolders, "theme")
 	folder.Subfolders = RemoveItemFromStringArray(folder.Subfolders, "js")
@@ -0,0 +1,421 @@
+package generator
+
+import (
+	"archive/zip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FS represents a filesystem that fotoDen's generator reads images from,
+// and writes websites into. Every function in the generator that works
+// on files takes an FS, so that a website can be generated somewhere other
+// than the OS filesystem (e.g., into memory, in order to put it into a zip
+// archive, or to upload it somewhere).
+//
+// Names are given in the same form as they would be to the os package.
+// Errors should be *os.PathError, so that os.IsNotExist and os.IsExist work on them.
+type FS interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm os.FileMode) error
+	ReadDir(name string) ([]os.FileInfo, error) // sorted by name, like ioutil.ReadDir
+	Stat(name string) (os.FileInfo, error)
+	Mkdir(name string, perm os.FileMode) error
+	MkdirAll(name string, perm os.FileMode) error
+	Remove(name string) error
+}
+
+// OSFS is an FS that uses the OS filesystem.
+type OSFS struct{}
+
+func (OSFS) ReadFile(name string) ([]byte, error) { return ioutil.ReadFile(name) }
+func (OSFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	return ioutil.WriteFile(name, data, perm)
+}
+func (OSFS) ReadDir(name string) ([]os.FileInfo, error)   { return ioutil.ReadDir(name) }
+func (OSFS) Stat(name string) (os.FileInfo, error)        { return os.Stat(name) }
+func (OSFS) Mkdir(name string, perm os.FileMode) error    { return os.Mkdir(name, perm) }
+func (OSFS) MkdirAll(name string, perm os.FileMode) error { return os.MkdirAll(name, perm) }
+func (OSFS) Remove(name string) error                     { return os.Remove(name) }
+func (OSFS) Rename(oldpath, newpath string) error         { return os.Rename(oldpath, newpath) }
+
+// MemFS is an FS that is kept entirely in memory.
+//
+// If Base is set, anything that is not in memory is read from Base instead -
+// this allows a website to be generated into memory from images in another FS.
+// Nothing is ever written to, or removed from, Base.
+type MemFS struct {
+	Base FS
+
+	mu    sync.RWMutex
+	files map[string]*memFile
+}
+
+type memFile struct {
+	name    string
+	data    []byte
+	mode    os.FileMode
+	modTime time.Time
+}
+
+func (f *memFile) Name() string       { return filepath.Base(f.name) }
+func (f *memFile) Size() int64        { return int64(len(f.data)) }
+func (f *memFile) Mode() os.FileMode  { return f.mode }
+func (f *memFile) ModTime() time.Time { return f.modTime }
+func (f *memFile) IsDir() bool        { return f.mode.IsDir() }
+func (f *memFile) Sys() interface{}   { return nil }
+
+// NewMemFS creates a new, empty MemFS.
+func NewMemFS() *MemFS {
+	return &MemFS{files: make(map[string]*memFile)}
+}
+
+// isRoot checks if a cleaned name is the root of the filesystem (either
+// the root directory, or the current directory), which always exists.
+func isRoot(name string) bool {
+	return name == "." || name == string(filepath.Separator) || filepath.Dir(name) == name
+}
+
+// stat returns the file with the cleaned name, either from memory or from Base.
+// The lock must be held.
+func (m *MemFS) stat(name string) (os.FileInfo, error) {
+	if f, ok := m.files[name]; ok {
+		return f, nil
+	}
+
+	if isRoot(name) {
+		return &memFile{name: name, mode: os.ModeDir | 0755}, nil
+	}
+
+	if m.Base != nil {
+		return m.Base.Stat(name)
+	}
+
+	return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+}
+
+// checkParent returns an error if the parent of the cleaned name is not a directory.
+// The lock must be held.
+func (m *MemFS) checkParent(op string, name string) error {
+	p, err := m.stat(filepath.Dir(name))
+	if err != nil {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrNotExist}
+	}
+
+	if !p.IsDir() {
+		return &os.PathError{Op: op, Path: name, Err: os.ErrInvalid}
+	}
+
+	return nil
+}
+
+func (m *MemFS) ReadFile(name string) ([]byte, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	name = filepath.Clean(name)
+	f, ok := m.files[name]
+	if !ok {
+		if m.Base != nil {
+			return m.Base.ReadFile(name)
+		}
+
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+
+	if f.IsDir() {
+		return nil, &os.PathError{Op: "read", Path: name, Err: os.ErrInvalid}
+	}
+
+	d := make([]byte, len(f.data))
+	copy(d, f.data)
+	return d, nil
+}
+
+func (m *MemFS) WriteFile(name string, data []byte, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	if f, err := m.stat(name); err == nil && f.IsDir() {
+		return &os.PathError{Op: "open", Path: name, Err: os.ErrInvalid}
+	}
+
+	err := m.checkParent("open", name)
+	if err != nil {
+		return err
+	}
+
+	d := make([]byte, len(data))
+	copy(d, data)
+	m.files[name] = &memFile{name: name, data: d, mode: perm.Perm(), modTime: time.Now()}
+	return nil
+}
+
+func (m *MemFS) ReadDir(name string) ([]os.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	name = filepath.Clean(name)
+	d, err := m.stat(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !d.IsDir() {
+		return nil, &os.PathError{Op: "readdirent", Path: name, Err: os.ErrInvalid}
+	}
+
+	entries := make(map[string]os.FileInfo)
+	if m.Base != nil {
+		b, err := m.Base.ReadDir(name)
+		if err == nil {
+			for _, f := range b {
+				entries[f.Name()] = f
+			}
+		}
+	}
+
+	for n, f := range m.files {
+		if n != name && filepath.Dir(n) == name {
+			entries[f.Name()] = f
+		}
+	}
+
+	dir := make([]os.FileInfo, 0, len(entries))
+	for _, f := range entries {
+		dir = append(dir, f)
+	}
+	sort.Slice(dir, func(i, j int) bool { return dir[i].Name() < dir[j].Name() })
+
+	return dir, nil
+}
+
+func (m *MemFS) Stat(name string) (os.FileInfo, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.stat(filepath.Clean(name))
+}
+
+func (m *MemFS) Mkdir(name string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.mkdir(filepath.Clean(name), perm)
+}
+
+// mkdir creates a directory with the cleaned name. The lock must be held.
+func (m *MemFS) mkdir(name string, perm os.FileMode) error {
+	if _, err := m.stat(name); err == nil {
+		return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrExist}
+	}
+
+	err := m.checkParent("mkdir", name)
+	if err != nil {
+		return err
+	}
+
+	m.files[name] = &memFile{name: name, mode: os.ModeDir | perm.Perm(), modTime: time.Now()}
+	return nil
+}
+
+func (m *MemFS) MkdirAll(name string, perm os.FileMode) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	if f, err := m.stat(name); err == nil {
+		if !f.IsDir() {
+			return &os.PathError{Op: "mkdir", Path: name, Err: os.ErrInvalid}
+		}
+
+		return nil
+	}
+
+	dirs := []string{name}
+	for p := filepath.Dir(name); !isRoot(p); p = filepath.Dir(p) {
+		if _, err := m.stat(p); err == nil {
+			break
+		}
+
+		dirs = append(dirs, p)
+	}
+
+	for i := len(dirs) - 1; i >= 0; i-- {
+		err := m.mkdir(dirs[i], perm)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (m *MemFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	name = filepath.Clean(name)
+	f, ok := m.files[name]
+	if !ok {
+		if _, err := m.stat(name); err == nil {
+			return &os.PathError{Op: "remove", Path: name, Err: os.ErrPermission}
+		}
+
+		return &os.PathError{Op: "remove", Path: name, Err: os.ErrNotExist}
+	}
+
+	if f.IsDir() {
+		for n := range m.files {
+			if n != name && filepath.Dir(n) == name {
+				return &os.PathError{Op: "remove", Path: name, Err: os.ErrExist}
+			}
+		}
+	}
+
+	delete(m.files, name)
+	return nil
+}
+
+// Walk walks the file tree in an FS, starting from root, in the same way as filepath.Walk.
+func Walk(fsys FS, root string, fn filepath.WalkFunc) error {
+	info, err := fsys.Stat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walk(fsys, root, info, fn)
+	}
+
+	if err == filepath.SkipDir {
+		return nil
+	}
+
+	return err
+}
+
+func walk(fsys FS, name string, info os.FileInfo, fn filepath.WalkFunc) error {
+	if !info.IsDir() {
+		return fn(name, info, nil)
+	}
+
+	dir, err := fsys.ReadDir(name)
+	err1 := fn(name, info, err)
+	if err != nil || err1 != nil {
+		return err1
+	}
+
+	for _, f := range dir {
+		err = walk(fsys, filepath.Join(name, f.Name()), f, fn)
+		if err != nil {
+			if !f.IsDir() || err != filepath.SkipDir {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Rename moves a file or directory in an FS from oldpath to newpath,
+// which must not exist yet. If the FS has a Rename method (as OSFS does),
+// that is used - otherwise, everything in oldpath is copied into newpath,
+// and then removed from oldpath.
+func Rename(fsys FS, oldpath string, newpath string) error {
+	if _, err := fsys.Stat(newpath); err == nil {
+		return &os.PathError{Op: "rename", Path: newpath, Err: os.ErrExist}
+	}
+
+	if r, ok := fsys.(interface{ Rename(string, string) error }); ok {
+		return r.Rename(oldpath, newpath)
+	}
+
+	files := make([]string, 0)
+	err := Walk(fsys, oldpath, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(oldpath, name)
+		if err != nil {
+			return err
+		}
+
+		files = append(files, name)
+		if info.IsDir() {
+			return fsys.Mkdir(filepath.Join(newpath, rel), info.Mode().Perm())
+		}
+
+		d, err := fsys.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		return fsys.WriteFile(filepath.Join(newpath, rel), d, info.Mode().Perm())
+	})
+	if err != nil {
+		return err
+	}
+
+	// children are always walked after their parents
+	for i := len(files) - 1; i >= 0; i-- {
+		err = fsys.Remove(files[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WriteZip writes everything in root, in an FS, into a zip archive.
+// Names in the archive are relative to root.
+func WriteZip(w io.Writer, fsys FS, root string) error {
+	z := zip.NewWriter(w)
+
+	err := Walk(fsys, root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		n, err := filepath.Rel(root, name)
+		if err != nil || n == "." {
+			return err
+		}
+
+		h, err := zip.FileInfoHeader(info)
+		if err != nil {
+			return err
+		}
+
+		h.Name = filepath.ToSlash(n)
+		if info.IsDir() {
+			h.Name += "/"
+			_, err = z.CreateHeader(h)
+			return err
+		}
+
+		h.Method = zip.Deflate
+		f, err := z.CreateHeader(h)
+		if err != nil {
+			return err
+		}
+
+		d, err := fsys.ReadFile(name)
+		if err != nil {
+			return err
+		}
+
+		_, err = f.Write(d)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	return z.Close()
+}
@@ -2,7 +2,6 @@ package generator
 
 import (
 	"encoding/json"
-	"io/ioutil"
 	"log"
 	"os"
 	"path"
@@ -21,6 +20,8 @@ type Config struct {
 	ImageSrcDirectory  string // where all source images are stored (default: ImageRootDirectory/src)
 	ImageMetaDirectory string // where all meta files per image are stored (default: ImageRootDirectory/meta)
 	ImageSizes         map[string]ImageScale
+	ImageBackend       string // what image backend to use (default: vips, or go if built with the purego tag)
+	Jobs               int    // how many images can be processed at once (default: the amount of CPUs)
 	WebSourceLocation  string // where all html/css/js files are stored for fotoDen's functionality
 	WebBaseURL         string // what the base URL is (aka, fotoDen's location)
 }
@@ -48,9 +49,16 @@ var DefaultConfig Config = Config{
 
 // CurrentConfig represents the current generator config, and can be used as reference
 // for any package that calls fotoDen/generator.
+//
+// Deprecated: CurrentConfig only exists for functions that do not take a Config.
+// Pass a Config around instead, and use SetImageBackend and SetJobs for the image
+// backend and the amount of jobs.
 var CurrentConfig Config
 
 // WorkingDirectory is the current working directory that fotoDen was started in.
+//
+// Deprecated: no function in the generator changes the working directory anymore,
+// so this is always the same as os.Getwd.
 var WorkingDirectory, _ = os.Getwd()
 
 // Verbose is used toggle verbose statements - when toggled, prints what the generator is doing to console.
@@ -62,17 +70,13 @@ func verbose(print string) {
 	}
 }
 
-// WriteJSON writes a struct as a JSON file to a specified pathname.
+// WriteJSON writes a struct as a JSON file to a specified pathname in an FS.
 // Takes a filepath, a "mode", and an interface containing something that translates to valid JSON according to encoding/json.
 // Mode toggles between non-indented JSON, and indented JSON.
 // Returns an error if any occur.
-func WriteJSON(filePath string, mode string, iface interface{}) error {
-	file, err := os.OpenFile(filePath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0755)
-	if err != nil {
-		return err
-	}
-
+func WriteJSON(fsys FS, filePath string, mode string, iface interface{}) error {
 	var toWrite []byte
+	var err error
 
 	switch mode {
 	case "single":
@@ -87,7 +91,7 @@ func WriteJSON(filePath string, mode string, iface interface{}) error {
 		}
 	}
 
-	_, err = file.Write(toWrite)
+	err = fsys.WriteFile(filePath, toWrite, 0755)
 	if err != nil {
 		return err
 	}
@@ -95,10 +99,10 @@ func WriteJSON(filePath string, mode string, iface interface{}) error {
 	return nil
 }
 
-// ReadJSON reads a JSON file from a pathname, and puts it into the specified interface.
+// ReadJSON reads a JSON file from a pathname in an FS, and puts it into the specified interface.
 // Returns an error if any occur.
-func ReadJSON(filePath string, iface interface{}) error {
-	file, err := ioutil.ReadFile(filePath)
+func ReadJSON(fsys FS, filePath string, iface interface{}) error {
+	file, err := fsys.ReadFile(filePath)
 	if err != nil {
 		return err
 	}
@@ -113,7 +117,7 @@ func ReadJSON(filePath string, iface interface{}) error {
 
 // OpenfotoDenConfig sets the current fotoDen generator configuration to this
 func OpenConfig(configLocation string) error {
-	err := ReadJSON(configLocation, &CurrentConfig)
+	err := ReadJSON(OSFS{}, configLocation, &CurrentConfig)
 	if err != nil {
 		return err
 	}
@@ -123,7 +127,7 @@ func OpenConfig(configLocation string) error {
 
 // WritefotoDenConfig attempts to write CurrentConfig to a new file at configLocation.
 func WriteConfig(config Config, configLocation string) error {
-	err := WriteJSON(configLocation, "multi", config)
+	err := WriteJSON(OSFS{}, configLocation, "multi", config)
 	if err != nil {
 		return err
 	}
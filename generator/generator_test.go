@@ -1,28 +1,81 @@
 package generator
 
 import (
+	"archive/zip"
+	"bytes"
 	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
 	"io/ioutil"
 	"os"
 	"path"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 )
 
+// testImages are the names of the images created by testFS, in order.
+var testImages = []string{"a.jpg", "b.jpg", "c.png"}
+
+// testFS creates a MemFS with a directory named images,
+// containing every image in testImages, as well as a file that is not an image.
+func testFS(t *testing.T) *MemFS {
+	fsys := NewMemFS()
+	err := fsys.Mkdir("images", 0755)
+	if err != nil {
+		t.Fatalf("Error - MemFS.Mkdir: " + fmt.Sprint(err))
+	}
+
+	for i, n := range testImages {
+		img := image.NewRGBA(image.Rect(0, 0, 200+i*40, 150))
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			for y := 0; y < img.Bounds().Dy(); y++ {
+				img.Set(x, y, color.RGBA{uint8(x), uint8(y), uint8(i * 80), 255})
+			}
+		}
+
+		var b bytes.Buffer
+		if filepath.Ext(n) == ".png" {
+			err = png.Encode(&b, img)
+		} else {
+			err = jpeg.Encode(&b, img, nil)
+		}
+		if err != nil {
+			t.Fatalf("Error - encoding test image: " + fmt.Sprint(err))
+		}
+
+		err = fsys.WriteFile(filepath.Join("images", n), b.Bytes(), 0644)
+		if err != nil {
+			t.Fatalf("Error - MemFS.WriteFile: " + fmt.Sprint(err))
+		}
+	}
+
+	err = fsys.WriteFile(filepath.Join("images", "notes.txt"), []byte("not an image"), 0644)
+	if err != nil {
+		t.Fatalf("Error - MemFS.WriteFile: " + fmt.Sprint(err))
+	}
+
+	return fsys
+}
+
 func TestJSONRW(t *testing.T) {
-	dir := t.TempDir()
+	fsys := NewMemFS()
 
 	type JSON struct {
 		Test string
 	}
 
-	err := WriteJSON(path.Join(dir, "tmp_json.json"), "single", JSON{"test"})
+	err := WriteJSON(fsys, "tmp_json.json", "single", JSON{"test"})
 	if err != nil {
 		t.Errorf("Error - WriteJSON: " + fmt.Sprint(err))
 	}
 
 	json := new(JSON)
 
-	err = ReadJSON(path.Join(dir, "tmp_json.json"), json)
+	err = ReadJSON(fsys, "tmp_json.json", json)
 	if err != nil {
 		t.Errorf("Error - ReadJSON: " + fmt.Sprint(err))
 	}
@@ -53,19 +106,21 @@ func TestFotoDenConfigRW(t *testing.T) {
 }
 
 func TestFolderInfoCRW(t *testing.T) {
-	dir := t.TempDir()
+	fsys := NewMemFS()
+	dir := "folder"
+	fsys.Mkdir(dir, 0755)
 
 	folder, err := GenerateFolderInfo(dir, dir)
 	if err != nil {
 		t.Errorf("Error - GenerateFolderInfo: " + fmt.Sprint(err))
 	}
 
-	err = folder.WriteFolderInfo((path.Join(dir, "folderInfo.json")))
+	err = folder.WriteFolderInfo(fsys, filepath.Join(dir, "folderInfo.json"))
 	if err != nil {
 		t.Errorf("Error - WriteFolderInfo: " + fmt.Sprint(err))
 	}
 
-	err = folder.ReadFolderInfo((path.Join(dir, "folderInfo.json")))
+	err = folder.ReadFolderInfo(fsys, filepath.Join(dir, "folderInfo.json"))
 	if err != nil {
 		t.Errorf("Error - ReadFolderInfo: " + fmt.Sprint(err))
 	}
@@ -73,65 +128,338 @@ func TestFolderInfoCRW(t *testing.T) {
 }
 
 func TestItemsInfoCRW(t *testing.T) {
-	dir := t.TempDir()
+	fsys := testFS(t)
 
-	items, err := GenerateItemInfo("../test_images")
+	items, err := GenerateItemInfo(fsys, "images")
 	if err != nil {
 		t.Errorf("Error - GenerateItemInfo: " + fmt.Sprint(err))
 	}
 
-	err = items.WriteItemsInfo(path.Join(dir, "itemsInfo.json"))
+	if fmt.Sprint(items.ItemsInFolder) != fmt.Sprint(testImages) {
+		t.Errorf("Error - GenerateItemInfo: expected %v, got %v", testImages, items.ItemsInFolder)
+	}
+
+	err = items.WriteItemsInfo(fsys, "itemsInfo.json")
 	if err != nil {
 		t.Errorf("Error - WriteItemsInfo: " + fmt.Sprint(err))
 	}
 
-	err = items.ReadItemsInfo(path.Join(dir, "itemsInfo.json"))
+	err = items.ReadItemsInfo(fsys, "itemsInfo.json")
 	if err != nil {
 		t.Errorf("Error: ReadItemsInfo: " + fmt.Sprint(err))
 	}
 	t.Log(fmt.Sprint(items))
 }
 
+// drain returns a progress channel that is continually emptied,
+// so that batch operations never block on it.
+func drain() chan int {
+	ch := make(chan int)
+	go func() {
+		for range ch {
+		}
+	}()
+
+	return ch
+}
+
+func TestSizedImageName(t *testing.T) {
+	n := SizedImageName("small", "image.png", "")
+	if n != "small_image.png" {
+		t.Errorf("Error - SizedImageName (fallback): " + n)
+	}
+
+	n = SizedImageName("small", "image.png", "webp")
+	if n != "small_image.png.webp" {
+		t.Errorf("Error - SizedImageName (webp): " + n)
+	}
+}
+
 func TestBatchCopyConvert(t *testing.T) {
-	dir := t.TempDir()
-	dir2 := path.Join(dir, t.TempDir())
+	fsys := testFS(t)
+	fsys.Mkdir("copy", 0755)
+	fsys.Mkdir("converted", 0755)
 
-	src, err := ioutil.ReadDir("../test_images")
+	src, err := fsys.ReadDir("images")
 	if err != nil {
 		t.Errorf("Error: Opening test images folder: " + fmt.Sprint(err))
 	}
-	srcfiles := GetArrayOfFiles(src)
+	srcfiles := IsolateImages(fsys, "images", GetArrayOfFiles(src))
 
-	defer os.Chdir(WorkingDirectory)
-	os.Chdir("../test_images")
-
-	err = BatchCopyFile(srcfiles, dir)
+	err = BatchCopyFile(fsys, srcfiles, "images", "copy", drain())
 	if err != nil {
 		t.Errorf("Error: BatchCopyFile: " + fmt.Sprint(err))
 	}
 
-	os.Chdir(dir)
-
-	err = BatchImageConversion(srcfiles, "test", dir2, ImageScale{ScalePercent: 0.99})
+	err = BatchImageConversion(fsys, srcfiles, "copy", "test", "converted", ImageScale{ScalePercent: 0.99, Formats: []string{"png"}}, drain())
 	if err != nil {
 		t.Errorf("Error: BatchImageConversion: " + fmt.Sprint(err))
 	}
+
+	for _, f := range srcfiles {
+		for _, n := range []string{SizedImageName("test", f, ""), SizedImageName("test", f, "png")} {
+			if _, err := fsys.Stat(filepath.Join("converted", n)); err != nil {
+				t.Errorf("Error: BatchImageConversion: %s was not generated", n)
+			}
+		}
+	}
 }
 
 func TestWebConfigCRW(t *testing.T) {
-	dir := t.TempDir()
+	fsys := NewMemFS()
 
-	webconfig := GenerateWebConfig("https://localhost/")
+	webconfig := DefaultConfig.WebConfig("https://localhost/")
 
-	err := webconfig.WriteWebConfig(path.Join(dir, "config.json"))
+	err := webconfig.WriteWebConfig(fsys, "config.json")
 	if err != nil {
 		t.Errorf("Error - WriteWebConfig: " + fmt.Sprint(err))
 	}
 
-	err = webconfig.ReadWebConfig(path.Join(dir, "config.json"))
+	err = webconfig.ReadWebConfig(fsys, "config.json")
 	if err != nil {
 		t.Errorf("Error - ReadWebConfig: " + fmt.Sprint(err))
 	}
 
 	t.Log(fmt.Sprint(webconfig))
 }
+
+func TestImageMetaCRW(t *testing.T) {
+	fsys := testFS(t)
+	fsys.Mkdir("meta", 0755)
+
+	err := GenerateImageMeta(fsys, filepath.Join("images", testImages[0]), "meta")
+	if err != nil {
+		t.Errorf("Error - GenerateImageMeta: " + fmt.Sprint(err))
+	}
+
+	meta := new(ImageMeta)
+	err = meta.ReadImageMeta(fsys, "meta", testImages[0])
+	if err != nil {
+		t.Errorf("Error - ReadImageMeta: " + fmt.Sprint(err))
+	}
+
+	if meta.Exif == nil || meta.Exif.Width == 0 || meta.Exif.Height == 0 {
+		t.Errorf("Error - GenerateImageMeta: image dimensions were not read")
+	}
+
+	t.Log(fmt.Sprint(meta.Exif))
+}
+
+func TestGoImageBackend(t *testing.T) {
+	fsys := testFS(t)
+
+	err := SetImageBackend("go")
+	if err != nil {
+		t.Fatalf("Error - SetImageBackend: " + fmt.Sprint(err))
+	}
+	defer SetImageBackend("")
+
+	if SetImageBackend("nonexistent") == nil {
+		t.Errorf("Error - SetImageBackend: nonexistent backend was set")
+	}
+
+	err = ResizeImage(fsys, filepath.Join("images", testImages[0]), "test.jpg", ImageScale{MaxHeight: 100}, ".", "jpeg")
+	if err != nil {
+		t.Errorf("Error - ResizeImage (go): " + fmt.Sprint(err))
+	}
+
+	b, _ := fsys.ReadFile("test.jpg")
+	w, h, err := goBackend{}.Size(b)
+	if err != nil {
+		t.Errorf("Error - ResizeImage (go): " + fmt.Sprint(err))
+	}
+
+	if h != 100 || w == 0 {
+		t.Errorf("Error - ResizeImage (go): image resized to %dx%d", w, h)
+	}
+
+	_, err = ImageFormat("avif")
+	if err == nil {
+		t.Errorf("Error - ImageFormat (go): avif should not be supported")
+	}
+}
+
+func TestCacheCRW(t *testing.T) {
+	fsys := testFS(t)
+
+	hash, err := HashFile(fsys, filepath.Join("images", testImages[0]))
+	if err != nil {
+		t.Errorf("Error - HashFile: " + fmt.Sprint(err))
+	}
+
+	cache := NewCache()
+	if !cache.SourceChanged("test.jpg", hash) {
+		t.Errorf("Error - SourceChanged: uncached image reported as unchanged")
+	}
+
+	cache.SetImage("test.jpg", hash)
+	cache.SetSize("test.jpg", "small", DefaultConfig.ImageSizes["small"])
+
+	err = cache.WriteCache(fsys, "itemsCache.json")
+	if err != nil {
+		t.Errorf("Error - WriteCache: " + fmt.Sprint(err))
+	}
+
+	cache = NewCache()
+	err = cache.ReadCache(fsys, "itemsCache.json")
+	if err != nil {
+		t.Errorf("Error - ReadCache: " + fmt.Sprint(err))
+	}
+
+	if cache.SizeChanged("test.jpg", hash, "small", DefaultConfig.ImageSizes["small"]) {
+		t.Errorf("Error - SizeChanged: cached size reported as changed")
+	}
+
+	if !cache.SizeChanged("test.jpg", hash, "small", ImageScale{MaxHeight: 100}) {
+		t.Errorf("Error - SizeChanged: rescaled size reported as unchanged")
+	}
+
+	if !cache.SizeChanged("test.jpg", "changed", "small", DefaultConfig.ImageSizes["small"]) {
+		t.Errorf("Error - SizeChanged: changed source reported as unchanged")
+	}
+
+	if s := cache.Stale([]string{"other.jpg"}); len(s) != 1 || s[0] != "test.jpg" {
+		t.Errorf("Error - Stale: " + fmt.Sprint(s))
+	}
+}
+
+func TestWorkerPool(t *testing.T) {
+	pool := NewWorkerPool(2)
+
+	var mu sync.Mutex
+	running, max, total := 0, 0, 0
+
+	job := func(i int) {
+		mu.Lock()
+		running++
+		total++
+		if running > max {
+			max = running
+		}
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pool.Run(4, job)
+		}()
+	}
+	wg.Wait()
+
+	if max > pool.Size() {
+		t.Errorf("Error - WorkerPool: %d jobs ran at once with %d workers", max, pool.Size())
+	}
+
+	if total != 12 {
+		t.Errorf("Error - WorkerPool: expected 12 jobs to run, got %d", total)
+	}
+}
+
+func TestBatchError(t *testing.T) {
+	fsys := testFS(t)
+	fsys.Mkdir("copy", 0755)
+
+	src, err := fsys.ReadDir("images")
+	if err != nil {
+		t.Errorf("Error: Opening test images folder: " + fmt.Sprint(err))
+	}
+	srcfiles := append(GetArrayOfFiles(src), "missing.jpg")
+
+	err = BatchCopyFile(fsys, srcfiles, "images", "copy", drain())
+	batchErr, ok := err.(BatchError)
+	if !ok {
+		t.Fatalf("Error - BatchCopyFile: expected BatchError, got " + fmt.Sprint(err))
+	}
+
+	if len(batchErr) != 1 || batchErr[0].File != "missing.jpg" || batchErr[0].Op != "copy" {
+		t.Errorf("Error - BatchCopyFile: unexpected errors: " + fmt.Sprint(batchErr))
+	}
+
+	batchErr = batchErr.Append(&FileError{File: "other.jpg", Op: "meta", Err: fmt.Errorf("test")})
+	if f := batchErr.Files(); len(f) != 2 || f[1] != "other.jpg" {
+		t.Errorf("Error - BatchError.Files: " + fmt.Sprint(f))
+	}
+}
+
+func TestMemFS(t *testing.T) {
+	fsys := NewMemFS()
+
+	err := fsys.WriteFile(filepath.Join("site", "index.html"), []byte("test"), 0644)
+	if !os.IsNotExist(err) {
+		t.Errorf("Error - MemFS.WriteFile: wrote into a missing directory: " + fmt.Sprint(err))
+	}
+
+	err = fsys.MkdirAll(filepath.Join("site", "album", "img"), 0755)
+	if err != nil {
+		t.Errorf("Error - MemFS.MkdirAll: " + fmt.Sprint(err))
+	}
+
+	err = fsys.Mkdir("site", 0755)
+	if !os.IsExist(err) {
+		t.Errorf("Error - MemFS.Mkdir: created an existing directory: " + fmt.Sprint(err))
+	}
+
+	err = fsys.WriteFile(filepath.Join("site", "index.html"), []byte("test"), 0644)
+	if err != nil {
+		t.Errorf("Error - MemFS.WriteFile: " + fmt.Sprint(err))
+	}
+
+	f, err := fsys.ReadDir("site")
+	if err != nil {
+		t.Errorf("Error - MemFS.ReadDir: " + fmt.Sprint(err))
+	}
+
+	if files, folders := GetArrayOfFilesAndFolders(f); fmt.Sprint(files, folders) != "[index.html] [album]" {
+		t.Errorf("Error - MemFS.ReadDir: unexpected entries: %v %v", files, folders)
+	}
+
+	err = fsys.Remove(filepath.Join("site", "album"))
+	if err == nil {
+		t.Errorf("Error - MemFS.Remove: removed a directory that is not empty")
+	}
+
+	overlay := NewMemFS()
+	overlay.Base = testFS(t)
+	err = CopyFile(overlay, filepath.Join("images", testImages[0]), filepath.Join("images", "copy.jpg"))
+	if err != nil {
+		t.Errorf("Error - CopyFile (overlay): " + fmt.Sprint(err))
+	}
+
+	if _, err := overlay.Base.Stat(filepath.Join("images", "copy.jpg")); err == nil {
+		t.Errorf("Error - MemFS: wrote into the base FS")
+	}
+
+	f, _ = overlay.ReadDir("images")
+	if len(f) != len(testImages)+2 {
+		t.Errorf("Error - MemFS.ReadDir (overlay): expected %d entries, got %d", len(testImages)+2, len(f))
+	}
+
+	var b bytes.Buffer
+	err = WriteZip(&b, fsys, "site")
+	if err != nil {
+		t.Errorf("Error - WriteZip: " + fmt.Sprint(err))
+	}
+
+	z, err := zip.NewReader(bytes.NewReader(b.Bytes()), int64(b.Len()))
+	if err != nil {
+		t.Fatalf("Error - WriteZip: " + fmt.Sprint(err))
+	}
+
+	names := make([]string, 0)
+	for _, f := range z.File {
+		names = append(names, f.Name)
+	}
+
+	if fmt.Sprint(names) != "[album/ album/img/ index.html]" {
+		t.Errorf("Error - WriteZip: unexpected files: %v", names)
+	}
+}
@@ -3,21 +3,28 @@ package generator
 import (
 	"fmt"
 	"path"
+	"path/filepath"
 	"strconv"
-
-	"github.com/h2non/bimg"
+	"strings"
 )
 
-// IsolateImages isolates images in an array.
+// IsolateImages isolates images in an array of file names within a directory in an FS.
+// If directory is an empty string, the file names are relative to the current working directory.
 //
 // Checks all image files at O(n), if a file is not an image, removes it from the current slice.
-func IsolateImages(files []string) []string {
+func IsolateImages(fsys FS, directory string, files []string) []string {
+	backend, err := CurrentImageBackend()
+	if err != nil {
+		fmt.Println(err)
+		return files
+	}
+
 	for i := 0; i < len(files); i++ {
-		image, err := bimg.Read(files[i])
+		image, err := fsys.ReadFile(filepath.Join(directory, files[i]))
 		if err != nil {
 			fmt.Println(err)
 		} else {
-			if bimg.DetermineImageType(image) == bimg.UNKNOWN {
+			if backend.Format(image) == "" {
 				verbose("File " + files[i] + " is not an image. Removing.")
 				files = RemoveItemFromStringArray(files, files[i]) // replace this with an append later
 				i--                                                // because now everything is shifted one backwards
@@ -30,10 +37,89 @@ func IsolateImages(files []string) []string {
 
 // ImageScale represents scaling options to be used by ResizeImage.
 // See ResizeImage for more information.
+//
+// Quality and Formats are optional. If Quality is zero, the default
+// quality of the image library is used. Every size is always generated
+// as a JPEG - Formats lists any extra formats (e.g., webp, avif) that
+// are generated next to the JPEG fallback.
 type ImageScale struct {
 	MaxHeight    int
 	MaxWidth     int
 	ScalePercent float64
+	Quality      int
+	Formats      []string
+}
+
+// imageFormats maps format names, as used in ImageScale, to the format names used by image backends.
+var imageFormats = map[string]string{
+	"jpeg": "jpeg",
+	"jpg":  "jpeg",
+	"webp": "webp",
+	"avif": "avif",
+	"png":  "png",
+}
+
+// ImageFormat returns the name of an image format as used by image backends.
+// Returns an error if the format is unknown, or if the current
+// image backend is unable to save in that format.
+func ImageFormat(name string) (string, error) {
+	f, ok := imageFormats[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf("ImageFormat: unknown image format: %s", name)
+	}
+
+	backend, err := CurrentImageBackend()
+	if err != nil {
+		return "", err
+	}
+
+	if !backend.CanSave(f) {
+		return "", fmt.Errorf("ImageFormat: saving as %s is not supported by the current image backend", name)
+	}
+
+	return f, nil
+}
+
+// SizedImageName returns the name of an image generated for a size.
+//
+// The JPEG fallback keeps the name of the source file with the size as a prefix
+// (e.g., small_image.png), since this is what fotoDen.js expects. Any other format
+// has its extension appended to that name (e.g., small_image.png.webp).
+func SizedImageName(size string, file string, format string) string {
+	n := size + "_" + path.Base(file)
+	if format == "" || imageFormats[strings.ToLower(format)] == "jpeg" {
+		return n
+	}
+
+	return n + "." + strings.ToLower(format)
+}
+
+// GenerateImageSize generates every format of a single size of an image
+// into the given directory in an FS - the JPEG fallback first, and then every
+// format in the ImageScale's Formats.
+func GenerateImageSize(fsys FS, file string, size string, scale ImageScale, dest string) error {
+	err := ResizeImage(fsys, file, SizedImageName(size, file, ""), scale, dest, "jpeg")
+	if err != nil {
+		return err
+	}
+
+	for _, f := range scale.Formats {
+		t, err := ImageFormat(f)
+		if err != nil {
+			return err
+		}
+
+		if t == "jpeg" {
+			continue
+		}
+
+		err = ResizeImage(fsys, file, SizedImageName(size, file, f), scale, dest, t)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // ResizeImage resizes a single image.
@@ -47,27 +133,33 @@ type ImageScale struct {
 // maxwidth is second for the same thing, but with flex set to column mode,
 // scalepercent is final for when the first two don't apply.
 //
-// The function will output the image to the given directory, without changing the name.
-// It will return an error if the filename given already exists in the destination directory.
-func ResizeImage(file string, imageName string, scale ImageScale, dest string, imageFormat bimg.ImageType) error {
-	image, err := bimg.Read(file)
+// The image is converted to imageFormat (e.g., jpeg), using the quality in the ImageScale if one is set.
+// All image operations are done through the current ImageBackend.
+//
+// The function will output the image to the given directory in the FS, without changing the name.
+// If the filename given already exists in the destination directory, it is overwritten.
+func ResizeImage(fsys FS, file string, imageName string, scale ImageScale, dest string, imageFormat string) error {
+	backend, err := CurrentImageBackend()
+	if err != nil {
+		return err
+	}
+
+	image, err := fsys.ReadFile(file)
 	if err != nil {
 		return err
 	}
 
-	imageType := bimg.DetermineImageType(image)
-	if imageType == bimg.UNKNOWN {
+	if backend.Format(image) == "" {
 		return fmt.Errorf("ResizeImage: Unknown file type. Skipping. Image: %s", imageName)
 	}
 
-	newImage, err := bimg.NewImage(image).Convert(imageFormat)
+	w, h, err := backend.Size(image)
 	if err != nil {
 		return err
 	}
 
-	size, err := bimg.NewImage(image).Size()
-	width := float64(size.Width)
-	height := float64(size.Height)
+	width := float64(w)
+	height := float64(h)
 
 	switch {
 	case scale.MaxHeight != 0:
@@ -85,36 +177,77 @@ func ResizeImage(file string, imageName string, scale ImageScale, dest string, i
 		return fmt.Errorf("ResizeImage: Image scaling undefined. Aborting. scale: " + fmt.Sprint(scale))
 	}
 
-	verbose("Resizing " + imageName + " to " + strconv.Itoa(int(width)) + "," + strconv.Itoa(int(height)) + " and attempting to place it in " + path.Join(dest, imageName))
-	newImage, err = bimg.NewImage(newImage).Resize(int(width), int(height))
+	verbose("Resizing " + imageName + " to " + strconv.Itoa(int(width)) + "," + strconv.Itoa(int(height)) + " and attempting to place it in " + filepath.Join(dest, imageName))
+	newImage, err := backend.Resize(image, int(width), int(height), imageFormat, scale.Quality)
 	if err != nil {
 		return err
 	}
 
-	destCheck, err := bimg.Read(path.Join(dest, imageName))
-	if destCheck != nil {
+	err = fsys.WriteFile(filepath.Join(dest, imageName), newImage, 0644)
+	if err != nil {
 		return err
 	}
 
-	bimg.Write(path.Join(dest, imageName), newImage)
 	return nil
 }
 
 // ImageMeta provides metadata such as name and description for an image file.
 // This is used by fotoDen on the web frontend to display custom per-image information.
 type ImageMeta struct {
-	ImageName string // The name of an image.
-	ImageDesc string // The description of an image.
+	ImageName string     // The name of an image.
+	ImageDesc string     // The description of an image.
+	ImageAlt  string     `json:",omitempty"` // The alternative text of an image, for when it can't be seen.
+	Tags      []string   `json:",omitempty"` // Any tags given to an image.
+	Exif      *ImageExif // The EXIF information of an image, read during generation.
+}
+
+// ReadImageMeta reads an ImageMeta struct from a file.
+//
+// Takes the same arguments as WriteImageMeta.
+func (meta *ImageMeta) ReadImageMeta(fsys FS, folder string, name string) error {
+	err := ReadJSON(fsys, filepath.Join(folder, name+".json"), meta)
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
 
 // WriteImageMeta writes an ImageMeta struct to a file.
 //
-// Takes two arguments: a folder destination, and a name.
+// Takes three arguments: an FS, a folder destination, and a name.
 // The name is automatically combined to create a [name].json file,
 // in order to ensure compatibility with fotoDen.
 // Writes the json file into the given folder.
-func (meta *ImageMeta) WriteImageMeta(folder string, name string) error {
-	err := WriteJSON(path.Join(folder, name+".json"), "multi", meta)
+func (meta *ImageMeta) WriteImageMeta(fsys FS, folder string, name string) error {
+	err := WriteJSON(fsys, filepath.Join(folder, name+".json"), "multi", meta)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GenerateImageMeta reads the EXIF information of an image file in an FS, and writes
+// an ImageMeta file for it into the given folder.
+//
+// If an ImageMeta file already exists for the image, everything
+// inside of it is kept, and only the EXIF information is updated.
+func GenerateImageMeta(fsys FS, file string, folder string) error {
+	name := path.Base(file)
+	meta := new(ImageMeta)
+
+	err := meta.ReadImageMeta(fsys, folder, name)
+	if err != nil {
+		verbose("No existing metadata for " + name + ", creating new metadata")
+	}
+
+	meta.Exif, err = ReadImageExif(fsys, file)
+	if err != nil {
+		verbose(fmt.Sprint(err))
+	}
+
+	err = meta.WriteImageMeta(fsys, folder, name)
 	if err != nil {
 		return err
 	}
@@ -122,10 +255,10 @@ func (meta *ImageMeta) WriteImageMeta(folder string, name string) error {
 	return nil
 }
 
-// MakeFolderThumbnail creates a thumbnail from a file into a destination directory.
+// MakeFolderThumbnail creates a thumbnail from a file into a destination directory in an FS.
 // This is only here to make fotoDen's command line tool look cleaner in code, and avoid importing more than needed.
-func MakeFolderThumbnail(file string, directory string) error {
-	err := ResizeImage(file, "thumb.jpg", ImageScale{MaxHeight: 500}, directory, bimg.JPEG)
+func MakeFolderThumbnail(fsys FS, file string, directory string) error {
+	err := ResizeImage(fsys, file, "thumb.jpg", ImageScale{MaxHeight: 500}, directory, "jpeg")
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,129 @@
+package generator
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+
+	// formats that the go backend can read, but not write
+	_ "image/gif"
+
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
+	_ "golang.org/x/image/webp"
+
+	"github.com/rwcarlsen/goexif/exif"
+	"golang.org/x/image/draw"
+)
+
+// goBackend is an ImageBackend written in pure Go, using the standard
+// image packages and golang.org/x/image. It can read JPEG, PNG, GIF,
+// BMP, TIFF and WebP images, but can only write JPEG and PNG images.
+type goBackend struct{}
+
+func (goBackend) Format(b []byte) string {
+	_, f, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return ""
+	}
+
+	return f
+}
+
+func (goBackend) Size(b []byte) (int, int, error) {
+	c, _, err := image.DecodeConfig(bytes.NewReader(b))
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return c.Width, c.Height, nil
+}
+
+func (goBackend) Resize(b []byte, width int, height int, format string, quality int) ([]byte, error) {
+	src, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, src.Bounds(), draw.Src, nil)
+
+	// libvips rotates images according to their EXIF orientation,
+	// so this does the same in order to match its output
+	if x, err := exif.Decode(bytes.NewReader(b)); err == nil {
+		dst = orientImage(dst, exifInt(x, exif.Orientation))
+	}
+
+	var out bytes.Buffer
+	switch format {
+	case "jpeg":
+		if quality == 0 {
+			quality = 75 // the same default as libvips
+		}
+
+		err = jpeg.Encode(&out, dst, &jpeg.Options{Quality: quality})
+	case "png":
+		err = png.Encode(&out, dst)
+	default:
+		return nil, fmt.Errorf("go: cannot encode images as %s", format)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func (goBackend) CanSave(format string) bool {
+	return format == "jpeg" || format == "png"
+}
+
+// orientImage transforms an image according to an EXIF orientation value.
+// Orientations 5 through 8 swap the width and height of the image.
+func orientImage(src *image.RGBA, orientation int) *image.RGBA {
+	if orientation < 2 || orientation > 8 {
+		return src
+	}
+
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+
+	var dst *image.RGBA
+	if orientation >= 5 {
+		dst = image.NewRGBA(image.Rect(0, 0, h, w))
+	} else {
+		dst = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // mirrored horizontally
+				dx, dy = w-1-x, y
+			case 3: // rotated 180 degrees
+				dx, dy = w-1-x, h-1-y
+			case 4: // mirrored vertically
+				dx, dy = x, h-1-y
+			case 5: // transposed
+				dx, dy = y, x
+			case 6: // rotated 90 degrees clockwise
+				dx, dy = h-1-y, x
+			case 7: // transversed
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotated 90 degrees counter-clockwise
+				dx, dy = y, w-1-x
+			}
+
+			dst.SetRGBA(dx, dy, src.RGBAAt(src.Bounds().Min.X+x, src.Bounds().Min.Y+y))
+		}
+	}
+
+	return dst
+}
+
+func init() {
+	registerImageBackend("go", goBackend{}, false)
+}
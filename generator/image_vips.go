@@ -0,0 +1,63 @@
+//go:build !purego
+// +build !purego
+
+package generator
+
+import (
+	"fmt"
+
+	"github.com/h2non/bimg"
+)
+
+// vipsBackend is an ImageBackend that uses libvips via bimg.
+type vipsBackend struct{}
+
+var vipsTypes = map[string]bimg.ImageType{
+	"jpeg": bimg.JPEG,
+	"png":  bimg.PNG,
+	"webp": bimg.WEBP,
+	"avif": bimg.AVIF,
+	"gif":  bimg.GIF,
+	"tiff": bimg.TIFF,
+}
+
+func (vipsBackend) Format(image []byte) string {
+	t := bimg.DetermineImageType(image)
+	if t == bimg.UNKNOWN {
+		return ""
+	}
+
+	return bimg.ImageTypeName(t)
+}
+
+func (vipsBackend) Size(image []byte) (int, int, error) {
+	size, err := bimg.Size(image)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return size.Width, size.Height, nil
+}
+
+func (vipsBackend) Resize(image []byte, width int, height int, format string, quality int) ([]byte, error) {
+	t, ok := vipsTypes[format]
+	if !ok {
+		return nil, fmt.Errorf("vips: cannot encode images as %s", format)
+	}
+
+	return bimg.NewImage(image).Process(bimg.Options{
+		Width:   width,
+		Height:  height,
+		Type:    t,
+		Quality: quality,
+	})
+}
+
+func (vipsBackend) CanSave(format string) bool {
+	t, ok := vipsTypes[format]
+	return ok && bimg.IsTypeSupportedSave(t)
+}
+
+func init() {
+	registerImageBackend("vips", vipsBackend{}, true)
+}
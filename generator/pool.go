@@ -0,0 +1,107 @@
+package generator
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+)
+
+// WorkerPool represents a bounded pool of workers.
+// Every job given to a WorkerPool is run by one of its workers,
+// so that no more than a set amount of jobs are ever run at once.
+type WorkerPool struct {
+	jobs chan func()
+	size int
+}
+
+// NewWorkerPool creates a new WorkerPool with the given amount of workers.
+// If size is zero or less, the amount of CPUs available is used instead.
+func NewWorkerPool(size int) *WorkerPool {
+	if size <= 0 {
+		size = runtime.NumCPU()
+	}
+
+	verbose("Starting worker pool with " + strconv.Itoa(size) + " workers")
+	p := &WorkerPool{
+		jobs: make(chan func()),
+		size: size,
+	}
+
+	for i := 0; i < size; i++ {
+		go func() {
+			for fn := range p.jobs {
+				fn()
+			}
+		}()
+	}
+
+	return p
+}
+
+// Size returns the amount of workers in a WorkerPool.
+func (p *WorkerPool) Size() int {
+	return p.size
+}
+
+// Run runs fn n times in the pool, passing the index of each run to fn,
+// and waits until every run is finished.
+//
+// Run can be called from several goroutines at once - all of their jobs
+// share the same workers. Run must never be called from inside of a job,
+// as it would wait on a worker that will never be free.
+func (p *WorkerPool) Run(n int, fn func(int)) {
+	var wg sync.WaitGroup
+
+	wg.Add(n)
+	for i := 0; i < n; i++ {
+		index := i
+		p.jobs <- func() {
+			defer wg.Done()
+			fn(index)
+		}
+	}
+
+	wg.Wait()
+}
+
+var (
+	workers     *WorkerPool
+	workersSize int
+	workersMu   sync.Mutex
+)
+
+// SetJobs sets the amount of workers in the WorkerPool shared by all of the batch operations
+// in the generator. If jobs is zero or less, the amount of CPUs available is used instead.
+//
+// The shared pool is created the first time a batch operation runs, so this only has an effect
+// if it is called before then.
+func SetJobs(jobs int) {
+	workersMu.Lock()
+	defer workersMu.Unlock()
+
+	if workers != nil {
+		verbose("Worker pool already started, ignoring new amount of jobs")
+		return
+	}
+
+	workersSize = jobs
+}
+
+// Workers returns the WorkerPool shared by all of the batch operations in the generator.
+// The pool is created the first time this is called, with the amount of workers set by
+// SetJobs, or with the amount of workers set in CurrentConfig.Jobs if SetJobs was never called.
+func Workers() *WorkerPool {
+	workersMu.Lock()
+	defer workersMu.Unlock()
+
+	if workers == nil {
+		size := workersSize
+		if size == 0 {
+			size = CurrentConfig.Jobs
+		}
+
+		workers = NewWorkerPool(size)
+	}
+
+	return workers
+}
@@ -23,22 +23,32 @@ type WebImageSize struct {
 	SizeName  string `json:"sizeName"` // the semantic name of the size
 	Directory string `json:"dir"`      // the directory the size is stored in, relative to ImageRootDir
 	LocalBool bool   `json:"local"`    // whether to download it remotely or locally
+	// any extra formats the size is available in, next to the JPEG fallback
+	Formats []string `json:"formats"`
 }
 
 // GenerateWebConfig creates a new WebConfig object, and returns a WebConfig object with a populated ImageSizes
 // based on the current ScalingOptions map.
+//
+// Deprecated: GenerateWebConfig uses CurrentConfig. Use Config.WebConfig instead.
 func GenerateWebConfig(source string) *WebConfig {
+	return CurrentConfig.WebConfig(source)
+}
 
+// WebConfig creates a new WebConfig object, and returns a WebConfig object with a populated ImageSizes
+// based on the ImageSizes of the Config. Takes the URL that images are stored in, if any.
+func (config Config) WebConfig(source string) *WebConfig {
 	webconfig := new(WebConfig)
 	webconfig.PhotoURLBase = source
 
-	for k := range CurrentConfig.ImageSizes {
+	for k := range config.ImageSizes {
 		webconfig.ImageSizes = append(
 			webconfig.ImageSizes,
 			WebImageSize{
 				SizeName:  k,
 				Directory: k,
 				LocalBool: true,
+				Formats:   config.ImageSizes[k].Formats,
 			},
 		)
 	}
@@ -46,9 +56,9 @@ func GenerateWebConfig(source string) *WebConfig {
 	return webconfig
 }
 
-// ReadWebConfig reads a JSON file containing WebConfig fields into a WebConfig struct.
-func (config *WebConfig) ReadWebConfig(fpath string) error {
-	err := ReadJSON(fpath, config)
+// ReadWebConfig reads a JSON file in an FS containing WebConfig fields into a WebConfig struct.
+func (config *WebConfig) ReadWebConfig(fsys FS, fpath string) error {
+	err := ReadJSON(fsys, fpath, config)
 	if err != nil {
 		return err
 	}
@@ -56,9 +66,9 @@ func (config *WebConfig) ReadWebConfig(fpath string) error {
 	return nil
 }
 
-// WriteWebConfig writes a WebConfig struct into the specified path.
-func (config *WebConfig) WriteWebConfig(fpath string) error {
-	err := WriteJSON(fpath, "multi", config)
+// WriteWebConfig writes a WebConfig struct into the specified path in an FS.
+func (config *WebConfig) WriteWebConfig(fsys FS, fpath string) error {
+	err := WriteJSON(fsys, fpath, "multi", config)
 	if err != nil {
 		return err
 	}
@@ -4,10 +4,13 @@ go 1.16
 
 require (
 	github.com/h2non/bimg v1.1.5
+	github.com/pkg/sftp v1.13.4
+	github.com/rwcarlsen/goexif v0.0.0-20190401172101-9e8deecbddbd
 	github.com/spf13/cobra v1.1.1
-	github.com/vulppine/cmdio-go v0.1.3 // indirect
-	github.com/vulppine/neocities-go v0.0.0-20210313031309-6f0f38f643bf // indirect
-	github.com/yuin/goldmark v1.3.5 // indirect
+	github.com/vulppine/cmdio-go v0.1.3
+	github.com/yuin/goldmark v1.3.5
+	golang.org/x/crypto v0.0.0-20210817164053-32db794688a5
+	golang.org/x/image v0.0.0-20210220032944-ac19c3e999fb
 	golang.org/x/lint v0.0.0-20201208152925-83fdc39ff7b5 // indirect
 	golang.org/x/tools v0.1.0 // indirect
 	gopkg.in/yaml.v2 v2.4.0
@@ -95,7 +95,7 @@ let theme = {
     thumbnailContainer.appendChild(thumbnailAnchor)
     thumbnailContainer.setAttribute('class', 'fd-albumThumbnail')
 
-    thumbnailAnchor.appendChild(thumbnail)
+    thumbnailAnchor.appendChild(makePicture(thumbnail, photoName, thumbnailFrom))
     thumbnailAnchor.setAttribute('href', thumbnailLink.toString())
     thumbnailAnchor.setAttribute('class', 'fd-albumThumbnailLink')
 
@@ -314,6 +314,40 @@ function makePhotoURL (photoName, dir, localBool) {
   }
 }
 
+// makePicture
+//
+// Wraps an image in a <picture> element, with a <source> for every
+// extra format (e.g., WebP, AVIF) that the given size is available in.
+// The image itself stays as the JPEG fallback. If the image is already
+// in the document, the picture takes its place - if it is already in a
+// picture, that picture is used again, with its sources replaced.
+
+function makePicture (image, photoName, size) {
+  const s = imageSizes.get(size)
+  let picture = image.parentNode
+
+  if (picture === null || picture.tagName !== 'PICTURE') {
+    picture = document.createElement('picture')
+    picture.setAttribute('style', 'display: contents')
+    if (image.parentNode !== null) {
+      image.parentNode.replaceChild(picture, image)
+    }
+
+    picture.appendChild(image)
+  } else {
+    picture.querySelectorAll('source').forEach((source) => picture.removeChild(source))
+  }
+
+  s.formats.forEach((format) => {
+    const source = document.createElement('source')
+    source.setAttribute('type', 'image/' + format)
+    source.setAttribute('srcset', makePhotoURL(s.prefix + photoName + '.' + format, s.directory, s.localBool))
+    picture.insertBefore(source, image)
+  })
+
+  return picture
+}
+
 function PhotoObject () {
   this.name = ''
   this.desc = ''
@@ -419,15 +453,25 @@ class PhotoViewer extends Viewer {
                 photo.desc = 'No description provided...'
               } else {
                 photo.name = meta.ImageName
-                photo.desc = json.ImageDesc
+                photo.desc = meta.ImageDesc
+              }
+
+              if (meta.ImageAlt !== undefined && meta.ImageAlt !== '') {
+                this.container.querySelector('.fd-photo').setAttribute('alt', meta.ImageAlt)
               }
 
               setText(this.name, photo.name)
               setText(this.desc, photo.desc)
               setTitle([photo.name, photo.album])
+
+              // sent even without EXIF information, so that the theme
+              // never shows the EXIF information of another photo
+              photo.exif = meta.Exif !== undefined ? meta.Exif : null
+              this.container.dispatchEvent(new CustomEvent('fd-metaLoad', { bubbles: true, detail: meta }))
             })
         } else {
           photo.name = json.items[photo.index]
+          this.container.dispatchEvent(new CustomEvent('fd-metaLoad', { bubbles: true, detail: { Exif: null } }))
           setText(this.name, photo.name)
           setTitle([photo.name, photo.album])
         }
@@ -455,6 +499,9 @@ class PhotoViewer extends Viewer {
 
   setPhoto (image) {
     setText(name, image)
+    if (imageSizes.has(displayImageFrom.size)) {
+      makePicture(this.container.querySelector('.fd-photo'), image, displayImageFrom.size)
+    }
     this.container.querySelector('.fd-photo').src = makePhotoURL(
       displayImageFrom.prefix + image,
       imageSizes.get(displayImageFrom.size).directory,
@@ -734,7 +781,10 @@ function readConfig (info) {
     imageSizes.set(i.sizeName, {
       directory: [imageRootDir, i.dir].join('/'),
       prefix: i.sizeName + '_',
-      localBool: i.local
+      localBool: i.local,
+      formats: (i.formats || [])
+        .map(f => f.toLowerCase())
+        .filter(f => f !== 'jpeg' && f !== 'jpg')
     })
   })
 }
@@ -1,4 +1,4 @@
-/* global bootstrap, BaseURL, EXIF, getPageInfo, getAlbumURL, makePhotoURL, pages, imageSizes, thumbnailFrom, websiteTitle */
+/* global bootstrap, BaseURL, EXIF, getPageInfo, getAlbumURL, makePhotoURL, makePicture, pages, imageSizes, thumbnailFrom, websiteTitle */
 /* eslint-env browser */
 
 /**
@@ -55,19 +55,51 @@ async function justifyThumbnails (container) {
   }
 }
 
+function setEXIFText (element, text) {
+  if (element === null) { return }
+  // hidden rather than removed, as the next photo may have it
+  element.parentNode.parentNode.classList.toggle('d-none', text === undefined)
+  element.innerText = text === undefined ? '' : text
+}
+
+// EXIF information from a photo's metadata, if fotoDen generated it
+let metaEXIF = null
+
+function setMetaEXIFData (exif) {
+  function orUndefined (value) {
+    if (value === '' || value === 0) { return undefined }
+    return value
+  }
+
+  setEXIFText(document.querySelector('#exif-make'), orUndefined(exif.Make))
+  setEXIFText(document.querySelector('#exif-model'), orUndefined(exif.Model))
+  setEXIFText(document.querySelector('#exif-lens'), orUndefined(exif.Lens))
+  setEXIFText(document.querySelector('#exif-iso-speed'), orUndefined(exif.ISO))
+  setEXIFText(document.querySelector('#exif-flash'), undefined)
+  setEXIFText(document.querySelector('#exif-focal'), orUndefined(exif.FocalLength) && exif.FocalLength + 'mm')
+  setEXIFText(document.querySelector('#exif-aperture'), orUndefined(exif.Aperture) && 'f/' + exif.Aperture)
+  setEXIFText(document.querySelector('#exif-shutter'), orUndefined(exif.ShutterSpeed) && exif.ShutterSpeed + 's')
+  setEXIFText(document.querySelector('#exif-date'), orUndefined(exif.DateTaken) && new Date(exif.DateTaken).toLocaleString())
+  setEXIFText(document.querySelector('#exif-dimensions'), orUndefined(exif.Width) && exif.Width + ' × ' + exif.Height)
+}
+
 function getEXIFData (image) {
-  function setEXIFText (element, text) {
-    if (element === null) { return }
-    if (text === undefined) { element.parentNode.parentNode.remove(); return }
-    element.innerText = text
+  if (metaEXIF !== null) {
+    setMetaEXIFData(metaEXIF)
+    return
   }
+
   EXIF.getData(image, function () {
     setEXIFText(document.querySelector('#exif-make'), EXIF.getTag(this, 'Make'))
     setEXIFText(document.querySelector('#exif-model'), EXIF.getTag(this, 'Model'))
+    setEXIFText(document.querySelector('#exif-lens'), undefined)
     setEXIFText(document.querySelector('#exif-iso-speed'), EXIF.getTag(this, 'ISOSpeedRatings'))
     setEXIFText(document.querySelector('#exif-flash'), EXIF.getTag(this, 'Flash'))
     setEXIFText(document.querySelector('#exif-focal'), EXIF.getTag(this, 'FocalLength'))
     setEXIFText(document.querySelector('#exif-aperture'), EXIF.getTag(this, 'FNumber'))
+    setEXIFText(document.querySelector('#exif-shutter'), undefined)
+    setEXIFText(document.querySelector('#exif-date'), EXIF.getTag(this, 'DateTimeOriginal'))
+    setEXIFText(document.querySelector('#exif-dimensions'), undefined)
   })
 }
 
@@ -236,7 +268,7 @@ export function createThumbnail (index, name) {
   thumbnail.setAttribute('class', 'fd-albumThumbnailImage')
   thumbnail.setAttribute('src', makePhotoURL(imageSizes.get(thumbnailFrom).prefix + name, imageSizes.get(thumbnailFrom).directory, imageSizes.get(thumbnailFrom).localBool))
 
-  thumbnailAnchor.appendChild(thumbnail)
+  thumbnailAnchor.appendChild(makePicture(thumbnail, name, thumbnailFrom))
   thumbnailAnchor.href = thumbnailLink.toString()
 
   thumbnailAnchor.classList.add('fd-albumThumbnail', 'text-center')
@@ -296,6 +328,13 @@ export function init () {
     console.log(e.target)
   })
 
+  document.addEventListener('fd-metaLoad', e => {
+    metaEXIF = e.detail.Exif !== undefined ? e.detail.Exif : null
+    if (metaEXIF !== null) {
+      setMetaEXIFData(metaEXIF)
+    }
+  })
+
   document.addEventListener('fd-contentLoad', e => {
     console.log(e.target)
     if (e.target.classList.contains('fd-albumThumbnails')) {
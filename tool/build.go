@@ -2,126 +2,632 @@ package tool
 
 import (
 	"fmt"
-	"io/ioutil"
+	"log"
+	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 
-	"gopkg.in/yaml.v2"
+	"github.com/vulppine/fotoDen/generator"
 )
 
 // BuildFile represents a fotoDen build file.
+//
+// The images of an album are every image in ImageDir, plus every image in Images.
+// If ImageDir is set, Images are relative to ImageDir. Any image matching
+// a pattern in Exclude (either by its location, or by its name) is left out.
+//
+// Include, Use and Defaults are resolved when a build file is opened
+// (see OpenBuildYAML) - building a BuildFile ignores them.
 type BuildFile struct {
-	Name     string   `yaml:"name"`
-	Dir      string   `yaml:"dir"`
-	Desc     string   `yaml:"desc"`
-	Type     string   `yaml:"type"`
-	Thumb    string   `yaml:"thumb"`
-	Static   bool     `yaml:"static"`
-	ImageDir string   `yaml:"imageDir"`
-	Images   []string `yaml:"images,flow"`
-	Options  struct {
-		Copy     bool `yaml:"copy"`
-		Sort     bool `yaml:"sort"`
-		Meta     bool `yaml:"metadata"`
-		Gensizes bool `yaml:"generateSizes"`
-	} `yaml:"imageOptions,flow"`
-	Subfolders []*BuildFile `yaml:"subfolders,flow"`
-}
-
-// OpenYAML opens a build file into a new BuildFile object.
-func (b *BuildFile) OpenBuildYAML(file string) error {
-	f, err := ioutil.ReadFile(file)
-	if checkError(err) {
-		return err
+	Name       string                 `yaml:"name"`
+	Dir        string                 `yaml:"dir,omitempty"`
+	Desc       string                 `yaml:"desc,omitempty"`
+	Type       string                 `yaml:"type"`
+	Thumb      string                 `yaml:"thumb,omitempty"`
+	Static     bool                   `yaml:"static,omitempty"`
+	ImageDir   string                 `yaml:"imageDir,omitempty"`
+	Images     []BuildImage           `yaml:"images,omitempty"`
+	Exclude    []string               `yaml:"exclude,flow,omitempty"`
+	Options    BuildImageOptions      `yaml:"imageOptions,flow,omitempty"`
+	Subfolders []*BuildFile           `yaml:"subfolders,omitempty"`
+	Include    []string               `yaml:"include,flow,omitempty"` // Build files that are added to Subfolders, relative to this build file.
+	Use        string                 `yaml:"use,omitempty"`          // The preset in Defaults that this entry, and every entry under it, is based on.
+	Defaults   map[string]BuildPreset `yaml:"defaults,omitempty"`     // Named presets, available to this entry and every entry under it.
+}
+
+// BuildImageOptions are the options used to generate the images of an album in a build file.
+type BuildImageOptions struct {
+	Copy     bool `yaml:"copy"`
+	Sort     bool `yaml:"sort"`
+	Meta     bool `yaml:"metadata"`
+	Gensizes bool `yaml:"generateSizes"`
+}
+
+// BuildPreset is a named set of defaults in a build file. An entry that uses
+// a preset gets everything in the preset that the entry does not set itself.
+type BuildPreset struct {
+	Thumb   string            `yaml:"thumb,omitempty"`
+	Static  bool              `yaml:"static,omitempty"`
+	Exclude []string          `yaml:"exclude,flow,omitempty"`
+	Options BuildImageOptions `yaml:"imageOptions,flow,omitempty"`
+}
+
+// BuildImage represents an entry in the images of a build file - either the
+// location of a single image, or a glob pattern matching several images in
+// the same directory (see filepath.Match), alongside metadata that is written
+// into the ImageMeta of every image it matches.
+//
+// In YAML, a BuildImage can either be just the location/pattern as a string,
+// or an object with the location/pattern in 'file'.
+type BuildImage struct {
+	File  string   `yaml:"file"`
+	Title string   `yaml:"title,omitempty"`
+	Desc  string   `yaml:"desc,omitempty"`
+	Alt   string   `yaml:"alt,omitempty"`
+	Tags  []string `yaml:"tags,flow,omitempty"`
+}
+
+func (i *BuildImage) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var f string
+	if unmarshal(&f) == nil {
+		i.File = f
+		return nil
 	}
 
-	err = yaml.Unmarshal(f, &b)
-	if checkError(err) {
-		return err
+	type plain BuildImage
+	return unmarshal((*plain)(i))
+}
+
+func (i BuildImage) MarshalYAML() (interface{}, error) {
+	if !i.hasMeta() {
+		return i.File, nil
 	}
 
-	return nil
+	type plain BuildImage
+	return plain(i), nil
+}
+
+// hasMeta checks if a BuildImage has any metadata.
+func (i BuildImage) hasMeta() bool {
+	return i.Title != "" || i.Desc != "" || i.Alt != "" || len(i.Tags) > 0
+}
+
+// merge sets any metadata in j onto i.
+func (i *BuildImage) merge(j *BuildImage) {
+	if j.Title != "" {
+		i.Title = j.Title
+	}
+
+	if j.Desc != "" {
+		i.Desc = j.Desc
+	}
+
+	if j.Alt != "" {
+		i.Alt = j.Alt
+	}
+
+	if len(j.Tags) > 0 {
+		i.Tags = j.Tags
+	}
+}
+
+// path returns the location of the folder/album in a build file,
+// when it is built into the given folder.
+func (b *BuildFile) path(folder string) string {
+	dir := b.Dir
+	if dir == "" {
+		dir = b.Name
+	}
+
+	if filepath.IsAbs(dir) {
+		return dir
+	}
+
+	return filepath.Join(folder, dir)
+}
+
+// albumOptions returns the options used to generate the album in a build file.
+// Metadata is always generated if any image in the build file has metadata.
+func (b *BuildFile) albumOptions() GeneratorOptions {
+	meta := b.Options.Meta
+	for _, i := range b.Images {
+		meta = meta || i.hasMeta()
+	}
+
+	return GeneratorOptions{
+		ImageGen: true,
+		Source:   b.ImageDir,
+		Copy:     b.Options.Copy,
+		Sort:     b.Options.Sort,
+		Meta:     meta,
+		Static:   b.Static,
+		Gensizes: b.Options.Gensizes,
+	}
+}
+
+// excluded checks if an image in a build file is excluded from its album.
+func (b *BuildFile) excluded(image string) bool {
+	rel := image
+	if b.ImageDir != "" {
+		if r, err := filepath.Rel(b.ImageDir, image); err == nil {
+			rel = r
+		}
+	}
+
+	for _, e := range b.Exclude {
+		for _, n := range []string{rel, filepath.Base(image)} {
+			if ok, _ := filepath.Match(e, n); ok {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// imageDirs returns every directory that the images of the album in a build file
+// are in: its image directory, followed by the directories of its individual images.
+func (b *BuildFile) imageDirs() []string {
+	dirs := make([]string, 0)
+	seen := make(map[string]bool)
+
+	add := func(d string) {
+		d = filepath.Clean(d)
+		if !seen[d] {
+			seen[d] = true
+			dirs = append(dirs, d)
+		}
+	}
+
+	if b.ImageDir != "" {
+		add(b.ImageDir)
+	}
+
+	for _, i := range b.Images {
+		add(filepath.Dir(b.imagePath(i.File)))
+	}
+
+	return dirs
+}
+
+// declares checks if an image is one of the images of the album in a build file,
+// without reading the directories that it and the other images are in.
+func (b *BuildFile) declares(image string) bool {
+	image = filepath.Clean(image)
+	if b.excluded(image) {
+		return false
+	}
+
+	if b.ImageDir != "" && filepath.Dir(image) == filepath.Clean(b.ImageDir) {
+		return true
+	}
+
+	for _, i := range b.Images {
+		if ok, _ := filepath.Match(filepath.Clean(b.imagePath(i.File)), image); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// imagePath returns the location of an image (or glob pattern) in a build file,
+// which is relative to the image directory if it is set.
+func (b *BuildFile) imagePath(file string) string {
+	if b.ImageDir != "" && !filepath.IsAbs(file) {
+		return filepath.Join(b.ImageDir, file)
+	}
+
+	return file
+}
+
+// albumImages returns the location of every image in the album in a build file
+// (the images in its image directory, followed by its individual images),
+// as well as the metadata of every image that has any, by name.
+func (b *BuildFile) albumImages(fsys generator.FS) ([]string, map[string]*BuildImage, error) {
+	images := make([]string, 0)
+	meta := make(map[string]*BuildImage)
+	seen := make(map[string]bool)
+
+	add := func(i string) {
+		i = filepath.Clean(i)
+		if !seen[i] && !b.excluded(i) {
+			seen[i] = true
+			images = append(images, i)
+		}
+	}
+
+	if b.ImageDir != "" {
+		items, err := generator.GenerateItemInfo(fsys, b.ImageDir)
+		if checkError(err) {
+			return nil, nil, err
+		}
+
+		if b.Options.Sort {
+			sort.Strings(items.ItemsInFolder)
+		}
+
+		for _, i := range items.ItemsInFolder {
+			add(filepath.Join(b.ImageDir, i))
+		}
+	}
+
+	for _, i := range b.Images {
+		files, err := glob(fsys, b.imagePath(i.File))
+		if checkError(err) {
+			return nil, nil, err
+		}
+
+		for _, f := range files {
+			add(f)
+			if !i.hasMeta() || b.excluded(f) {
+				continue
+			}
+
+			n := filepath.Base(f)
+			if meta[n] == nil {
+				meta[n] = &BuildImage{File: n}
+			}
+
+			meta[n].merge(&i)
+		}
+	}
+
+	return images, meta, nil
 }
 
 // BuildFromYAML is the entry point to the fotoDen
 // website build system. It takes a YAML file,
 // with the correct structure, and creates a website
 // in the given folder.
+//
+// Images that fail to generate do not stop the build -
+// they are returned together as a generator.BatchError
+// once everything else is built.
+//
+// Deprecated: use Site.Build instead.
 func (b *BuildFile) Build(folder string) error {
+	return globalSite().Build(b, folder)
+}
+
+// glob returns every image in an FS matching a pattern. Only the last element of
+// a pattern can contain any wildcards. If the pattern has no wildcards, it is
+// returned as is, whether it exists or not.
+func glob(fsys generator.FS, pattern string) ([]string, error) {
+	dir, file := filepath.Split(pattern)
+	if !strings.ContainsAny(file, "*?[") {
+		return []string{pattern}, nil
+	}
+
+	if _, err := filepath.Match(file, ""); err != nil {
+		return nil, fmt.Errorf("%s: %w", pattern, err)
+	}
+
+	d, err := fsys.ReadDir(filepath.Clean(dir))
+	if checkError(err) {
+		return nil, err
+	}
+
+	matches := make([]string, 0)
+	for _, f := range generator.GetArrayOfFiles(d) {
+		if ok, _ := filepath.Match(file, f); ok {
+			matches = append(matches, f)
+		}
+	}
+
+	images := generator.IsolateImages(fsys, filepath.Clean(dir), matches)
+	for i := range images {
+		images[i] = filepath.Join(dir, images[i])
+	}
+
+	return images, nil
+}
+
+// imageMeta returns the ImageMeta of an image in the album in fpath,
+// with the metadata of a BuildImage set onto it, and whether it changed.
+func (s *Site) imageMeta(fpath string, i *BuildImage) (*generator.ImageMeta, bool, error) {
+	dir := filepath.Join(fpath, s.GeneratorConfig.ImageRootDirectory, s.GeneratorConfig.ImageMetaDirectory)
+	meta := new(generator.ImageMeta)
+
+	if s.exists(filepath.Join(dir, i.File+".json")) {
+		err := meta.ReadImageMeta(s.fsys(), dir, i.File)
+		if checkError(err) {
+			return nil, false, err
+		}
+	}
+
+	old := *meta
+	old.Tags = append([]string(nil), meta.Tags...)
+
+	if i.Title != "" {
+		meta.ImageName = i.Title
+	}
+
+	if i.Desc != "" {
+		meta.ImageDesc = i.Desc
+	}
+
+	if i.Alt != "" {
+		meta.ImageAlt = i.Alt
+	}
+
+	if len(i.Tags) > 0 {
+		meta.Tags = i.Tags
+	}
+
+	return meta, !reflect.DeepEqual(old, *meta), nil
+}
+
+// writeImageMeta writes the metadata of every image in a build file into
+// the album in fpath. Images without metadata are left alone, as is any
+// metadata that a BuildImage does not set.
+func (s *Site) writeImageMeta(fpath string, meta map[string]*BuildImage) error {
+	dir := filepath.Join(fpath, s.GeneratorConfig.ImageRootDirectory, s.GeneratorConfig.ImageMetaDirectory)
+
+	for _, i := range meta {
+		m, changed, err := s.imageMeta(fpath, i)
+		if checkError(err) {
+			return err
+		}
+
+		if changed {
+			verbose("Writing metadata of " + i.File)
+			err = m.WriteImageMeta(s.fsys(), dir, i.File)
+			if checkError(err) {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// Build builds the website in a build file into the given folder.
+//
+// Building is idempotent - the build file is reconciled against whatever
+// already exists in the folder. Folders and albums that do not exist are created,
+// and folders and albums that do exist have their name, description and thumbnail
+// updated (a name or description that is not in the build file is left as is).
+// The items of every album are synced with the images declared in the build file:
+// new or changed images are generated, and images that are no longer declared
+// are removed from the album. Folders and albums that are not in the build file
+// are left alone (see Site.Prune).
+//
+// Images that fail to generate do not stop the build -
+// they are returned together as a generator.BatchError
+// once everything else is built.
+func (s *Site) Build(b *BuildFile, folder string) error {
+	var batchErr generator.BatchError
+
 	verbose(fmt.Sprint(b))
+	fpath := b.path(folder)
+
 	switch b.Type {
-	case "folder":
-		genopts := GeneratorOptions{
-			Static: b.Static,
+	case "folder", "album":
+		err := s.buildFolder(b, fpath)
+		if checkError(err) {
+			if !isBatchError(err) {
+				return err
+			}
+
+			batchErr = batchErr.Append(err)
 		}
-		if b.Dir == "" {
-			b.Dir = b.Name
+	}
+
+	for _, f := range b.Subfolders {
+		err := s.Build(f, fpath)
+		if checkError(err) {
+			if !isBatchError(err) {
+				return err
+			}
+
+			batchErr = batchErr.Append(err)
 		}
-		err := GenerateFolder(
+	}
+
+	return batchErr.ErrorOrNil()
+}
+
+// buildFolder reconciles a single folder or album in a build file against fpath.
+func (s *Site) buildFolder(b *BuildFile, fpath string) error {
+	var imageErr error
+
+	infoPath := filepath.Join(fpath, "folderInfo.json")
+	created := !s.exists(infoPath)
+	if created {
+		// albums are created as folders first,
+		// and then have their items synced like any other album
+		err := s.generateFolder(
 			FolderMeta{
-				Name: b.Name,
-				Desc: b.Desc,
+				Name:  b.Name,
+				Desc:  b.Desc,
+				Thumb: b.Thumb,
 			},
-			filepath.Join(folder, b.Dir),
-			genopts,
+			fpath,
+			GeneratorOptions{Static: b.Static},
 		)
 		if checkError(err) {
 			return err
 		}
-	case "album":
-		genopts := GeneratorOptions{
-			ImageGen: true,
-			Copy:     b.Options.Copy,
-			Sort:     b.Options.Sort,
-			Meta:     b.Options.Meta,
-			Static:   b.Static,
-			Gensizes: b.Options.Gensizes,
-		}
-		if b.Dir == "" {
-			b.Dir = b.Name
-		}
-		if b.ImageDir != "" {
-			genopts.Source = b.ImageDir
-			err := GenerateFolder(
-				FolderMeta{
-					Name: b.Name,
-					Desc: b.Desc,
-				},
-				filepath.Join(folder, b.Dir),
-				genopts,
-			)
-			if checkError(err) {
-				return err
-			}
+	}
 
-			InsertImage(folder, "append", Genoptions, b.Images...)
-		} else {
-			err := GenerateFolder(
-				FolderMeta{
-					Name: b.Name,
-					Desc: b.Desc,
-				},
-				filepath.Join(folder, b.Dir),
-				genopts,
-			)
-			if checkError(err) {
+	f := new(generator.Folder)
+	err := f.ReadFolderInfo(s.fsys(), infoPath)
+	if checkError(err) {
+		return err
+	}
+
+	old := *f
+
+	if b.Name != "" {
+		f.Name = b.Name
+	}
+	if b.Desc != "" {
+		f.Desc = b.Desc
+	}
+	f.Static = b.Static
+
+	if !created && s.thumbChanged(f, b.Thumb, fpath) {
+		verbose("Updating thumbnail of " + fpath)
+		err = generator.MakeFolderThumbnail(s.fsys(), b.Thumb, fpath)
+		if checkError(err) {
+			return err
+		}
+
+		f.Thumbnail = true
+	}
+
+	if b.Type == "album" {
+		images, meta, err := b.albumImages(s.fsys())
+		if checkError(err) {
+			return err
+		}
+
+		n, err := s.syncItems(fpath, images, b.albumOptions())
+		if checkError(err) {
+			if !isBatchError(err) {
 				return err
 			}
 
-			InsertImage(folder, "append", Genoptions, b.Images...)
+			imageErr = err
 		}
+
+		err = s.writeImageMeta(fpath, meta)
+		if checkError(err) {
+			return err
+		}
+
+		f.Type = "album"
+		f.ItemAmount = n
 	}
 
-	for _, f := range b.Subfolders {
-		if f.Dir == "" {
-			f.Dir = f.Name
+	if !created && reflect.DeepEqual(old, *f) {
+		verbose(fpath + " is up to date")
+		return imageErr
+	}
+
+	err = f.WriteFolderInfo(s.fsys(), infoPath)
+	if checkError(err) {
+		return err
+	}
+
+	err = s.UpdateWeb(fpath)
+	if checkError(err) {
+		return err
+	}
+
+	return imageErr
+}
+
+// thumbChanged checks if the thumbnail of a folder needs to be made from thumb:
+// either because the folder does not have a thumbnail yet,
+// or because thumb was changed after the thumbnail was made.
+func (s *Site) thumbChanged(f *generator.Folder, thumb string, fpath string) bool {
+	if thumb == "" {
+		return false
+	}
+
+	if !f.Thumbnail {
+		return true
+	}
+
+	src, err := s.fsys().Stat(thumb)
+	if err != nil {
+		return true
+	}
+
+	t, err := s.fsys().Stat(filepath.Join(fpath, "thumb.jpg"))
+	if err != nil {
+		return true
+	}
+
+	return src.ModTime().After(t.ModTime())
+}
+
+// Prune removes every folder and album in the given folder that is not in a build file,
+// as if the build file was built into the given folder. Only fotoDen folders (folders
+// with a folderInfo.json) are removed - anything else is left alone.
+func (s *Site) Prune(b *BuildFile, folder string) error {
+	fpath := b.path(folder)
+
+	stale, err := s.staleFolders(b, fpath)
+	if checkError(err) {
+		return err
+	}
+
+	for _, f := range stale {
+		log.Println("Removing " + f)
+		err = s.removeAll(f)
+		if checkError(err) {
+			return err
 		}
+	}
 
-		if !filepath.IsAbs(b.Dir) {
-			verbose("filepath not absolute, joining and locating")
-			b.Dir, _ = filepath.Abs(filepath.Join(folder, b.Dir))
+	if len(stale) > 0 {
+		err = s.UpdateFolderSubdirectories(fpath)
+		if checkError(err) {
+			return err
+		}
+	}
+
+	for _, sf := range b.Subfolders {
+		err = s.Prune(sf, fpath)
+		if checkError(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// staleFolders returns every fotoDen folder in fpath
+// that is not a subfolder of the build file.
+func (s *Site) staleFolders(b *BuildFile, fpath string) ([]string, error) {
+	if !s.exists(filepath.Join(fpath, "folderInfo.json")) {
+		return nil, nil
+	}
+
+	declared := make(map[string]bool)
+	for _, sf := range b.Subfolders {
+		declared[filepath.Clean(sf.path(fpath))] = true
+	}
+
+	dir, err := s.fsys().ReadDir(fpath)
+	if checkError(err) {
+		return nil, err
+	}
+
+	stale := make([]string, 0)
+	for _, d := range generator.GetArrayOfFolders(dir) {
+		p := filepath.Join(fpath, d)
+		if !declared[p] && s.exists(filepath.Join(p, "folderInfo.json")) {
+			stale = append(stale, p)
 		}
+	}
+
+	return stale, nil
+}
+
+// removeAll removes a directory, and everything in it, from the FS of the site.
+func (s *Site) removeAll(dir string) error {
+	files := make([]string, 0)
+	err := generator.Walk(s.fsys(), dir, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		files = append(files, name)
+		return nil
+	})
+	if checkError(err) {
+		return err
+	}
 
-		err := f.Build(b.Dir)
+	// children are always walked after their parents
+	for i := len(files) - 1; i >= 0; i-- {
+		err = s.fsys().Remove(files[i])
 		if checkError(err) {
 			return err
 		}
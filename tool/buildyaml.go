@@ -0,0 +1,327 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// yamlMap is a YAML mapping, as decoded by the yaml package.
+type yamlMap = map[interface{}]interface{}
+
+// presetKeys are the keys of a BuildFile that a BuildPreset can set.
+var presetKeys = []string{"thumb", "static", "exclude", "imageOptions"}
+
+// OpenBuildYAML opens a build file into a BuildFile object.
+//
+// Build files are decoded strictly - unknown fields, values of the wrong type
+// and unknown folder types are errors (see BuildFileError), rather than being
+// silently ignored.
+//
+// Every build file in Include is opened (relative to the directory of the
+// build file including it) and added to the end of Subfolders. The images,
+// image directories and thumbnails of an included build file are relative to
+// its own directory, rather than the working directory. Every entry
+// that uses a preset (either by itself, or through the entry it is under)
+// gets everything in that preset that it does not set itself, with
+// imageOptions being merged option by option. A preset defined under an entry
+// replaces any preset of the same name defined above it.
+func (b *BuildFile) OpenBuildYAML(file string) error {
+	m, err := resolveBuildYAML(file, make(map[string]yamlMap), "", nil)
+	if checkError(err) {
+		return err
+	}
+
+	d, err := yaml.Marshal(m)
+	if checkError(err) {
+		return err
+	}
+
+	err = yaml.UnmarshalStrict(d, b)
+	if checkError(err) {
+		return err
+	}
+
+	return nil
+}
+
+// BuildFileError is an error in a single build file. If the build file
+// could not be decoded, Err is a *yaml.TypeError, which has the line of
+// every field that caused it.
+type BuildFileError struct {
+	File string
+	Err  error
+}
+
+func (e *BuildFileError) Error() string {
+	return e.File + ": " + e.Err.Error()
+}
+
+func (e *BuildFileError) Unwrap() error {
+	return e.Err
+}
+
+// WriteBuildYAML writes a build file into a YAML file.
+func (b *BuildFile) WriteBuildYAML(file string) error {
+	d, err := yaml.Marshal(b)
+	if checkError(err) {
+		return err
+	}
+
+	err = ioutil.WriteFile(file, d, 0644)
+	if checkError(err) {
+		return err
+	}
+
+	return nil
+}
+
+// readBuildYAML reads a build file as a YAML mapping, after checking
+// that it strictly decodes into a BuildFile.
+func readBuildYAML(file string) (yamlMap, error) {
+	f, err := ioutil.ReadFile(file)
+	if checkError(err) {
+		return nil, err
+	}
+
+	// presets and includes are only resolved after this, but this is
+	// the only point where the lines of the file are still known
+	err = yaml.UnmarshalStrict(f, new(BuildFile))
+	if err != nil {
+		return nil, &BuildFileError{file, err}
+	}
+
+	m := make(yamlMap)
+	err = yaml.Unmarshal(f, &m)
+	if err != nil {
+		return nil, &BuildFileError{file, err}
+	}
+
+	return m, nil
+}
+
+// resolveBuildYAML reads a build file, and resolves its includes and presets.
+// Files is every build file currently being resolved, in order to catch
+// build files that include themselves.
+func resolveBuildYAML(file string, presets map[string]yamlMap, use string, files []string) (yamlMap, error) {
+	abs, err := filepath.Abs(file)
+	if checkError(err) {
+		return nil, err
+	}
+
+	for _, f := range files {
+		if f == abs {
+			return nil, &BuildFileError{file, fmt.Errorf("build file includes itself")}
+		}
+	}
+
+	m, err := readBuildYAML(file)
+	if checkError(err) {
+		return nil, err
+	}
+
+	// the root build file is relative to the working directory
+	if len(files) > 0 {
+		relocateBuildEntry(m, filepath.Dir(file))
+	}
+
+	err = resolveBuildEntry(m, filepath.Dir(file), presets, use, append(files, abs))
+	if err != nil {
+		var fileErr *BuildFileError
+		if errors.As(err, &fileErr) {
+			return nil, err
+		}
+
+		return nil, &BuildFileError{file, err}
+	}
+
+	return m, nil
+}
+
+// resolveBuildEntry resolves the includes and presets of an entry in a build file,
+// and every entry under it. Includes are relative to dir.
+func resolveBuildEntry(m yamlMap, dir string, presets map[string]yamlMap, use string, files []string) error {
+	if t, ok := m["type"]; ok && t != "folder" && t != "album" {
+		return fmt.Errorf("%v: unknown type %v (must be either folder or album)", m["name"], t)
+	}
+
+	if d, ok := m["defaults"]; ok {
+		defaults, ok := d.(yamlMap)
+		if !ok {
+			return fmt.Errorf("defaults must be a mapping of preset names to presets")
+		}
+
+		// presets under an entry override presets of the same name above it
+		p := make(map[string]yamlMap, len(presets)+len(defaults))
+		for k, v := range presets {
+			p[k] = v
+		}
+
+		for k, v := range defaults {
+			preset, ok := v.(yamlMap)
+			if !ok {
+				return fmt.Errorf("preset %v must be a mapping", k)
+			}
+
+			for pk := range preset {
+				if !isPresetKey(pk) {
+					return fmt.Errorf("preset %v: %v can't be set by a preset", k, pk)
+				}
+			}
+
+			p[fmt.Sprint(k)] = preset
+		}
+
+		presets = p
+	}
+
+	if u, ok := m["use"]; ok {
+		use = fmt.Sprint(u)
+	}
+
+	if use != "" {
+		preset, ok := presets[use]
+		if !ok {
+			return fmt.Errorf("preset %s does not exist", use)
+		}
+
+		mergeYAML(m, preset)
+	}
+
+	subfolders := make([]interface{}, 0)
+	if sf, ok := m["subfolders"]; ok && sf != nil {
+		l, ok := sf.([]interface{})
+		if !ok {
+			return fmt.Errorf("subfolders must be a list")
+		}
+
+		for _, e := range l {
+			sm, ok := e.(yamlMap)
+			if !ok {
+				return fmt.Errorf("every subfolder must be a mapping")
+			}
+
+			err := resolveBuildEntry(sm, dir, presets, use, files)
+			if err != nil {
+				return err
+			}
+
+			subfolders = append(subfolders, sm)
+		}
+	}
+
+	if inc, ok := m["include"]; ok && inc != nil {
+		l, ok := inc.([]interface{})
+		if !ok {
+			return fmt.Errorf("include must be a list")
+		}
+
+		for _, f := range l {
+			p := fmt.Sprint(f)
+			if !filepath.IsAbs(p) {
+				p = filepath.Join(dir, p)
+			}
+
+			sm, err := resolveBuildYAML(p, presets, use, files)
+			if err != nil {
+				return err
+			}
+
+			subfolders = append(subfolders, sm)
+		}
+	}
+
+	if len(subfolders) > 0 {
+		m["subfolders"] = subfolders
+	}
+
+	return nil
+}
+
+// relocateBuildEntry makes the relative images, image directory and thumbnail of
+// an entry in a build file (and of its presets, and every entry under it)
+// relative to dir. Images are only relocated if the entry has no image
+// directory, as they are relative to it otherwise.
+func relocateBuildEntry(m yamlMap, dir string) {
+	relocate := func(v interface{}) interface{} {
+		p, ok := v.(string)
+		if !ok || p == "" || filepath.IsAbs(p) {
+			return v
+		}
+
+		return filepath.Join(dir, p)
+	}
+
+	if t, ok := m["thumb"]; ok {
+		m["thumb"] = relocate(t)
+	}
+
+	if d, ok := m["imageDir"]; ok && d != "" {
+		m["imageDir"] = relocate(d)
+	} else if l, ok := m["images"].([]interface{}); ok {
+		for k, i := range l {
+			if im, ok := i.(yamlMap); ok {
+				im["file"] = relocate(im["file"])
+			} else {
+				l[k] = relocate(i)
+			}
+		}
+	}
+
+	if d, ok := m["defaults"].(yamlMap); ok {
+		for _, v := range d {
+			if preset, ok := v.(yamlMap); ok {
+				if t, ok := preset["thumb"]; ok {
+					preset["thumb"] = relocate(t)
+				}
+			}
+		}
+	}
+
+	if l, ok := m["subfolders"].([]interface{}); ok {
+		for _, e := range l {
+			if sm, ok := e.(yamlMap); ok {
+				relocateBuildEntry(sm, dir)
+			}
+		}
+	}
+}
+
+// isPresetKey checks if a key can be set by a preset.
+func isPresetKey(k interface{}) bool {
+	for _, p := range presetKeys {
+		if k == p {
+			return true
+		}
+	}
+
+	return false
+}
+
+// mergeYAML sets everything in src that is not in dst onto dst.
+// Mappings that are in both are merged in the same way.
+func mergeYAML(dst yamlMap, src yamlMap) {
+	for k, v := range src {
+		d, ok := dst[k]
+		if !ok {
+			dst[k] = v
+			continue
+		}
+
+		dm, dok := d.(yamlMap)
+		sm, sok := v.(yamlMap)
+		if dok && sok {
+			// copied, so that the preset itself isn't changed
+			c := make(yamlMap, len(dm))
+			for ck, cv := range dm {
+				c[ck] = cv
+			}
+
+			mergeYAML(c, sm)
+			dst[k] = c
+		}
+	}
+}
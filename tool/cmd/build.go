@@ -1,19 +1,41 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
 	"github.com/spf13/cobra"
+	"github.com/vulppine/fotoDen/generator"
 	"github.com/vulppine/fotoDen/tool"
 )
 
 func init() {
 	rootCmd.AddCommand(buildCmd)
+	buildCmd.Flags().BoolVarP(&watch, "watch", "w", false, "keep watching the images of albums (their image directories, and the directories of their listed images) after building, and update the albums when they change")
+	buildCmd.Flags().DurationVar(&debounce, "debounce", 2*time.Second, "how long an image directory has to stay the same before its changes are applied (with --watch)")
+	buildCmd.Flags().BoolVar(&dryRun, "dry-run", false, "print what the build would do, without building anything")
+	buildCmd.Flags().StringVarP(&output, "output", "o", "text", "the format to print a dry run in (text or json)")
+	buildCmd.Flags().BoolVar(&prune, "prune", false, "delete folders and albums that are not in the build file")
 }
 
 var (
+	watch    bool
+	dryRun   bool
+	output   string
+	prune    bool
 	buildCmd = &cobra.Command{
-		Use:   "build buildfile destination",
+		Use:   "build [--prune] [--watch | --dry-run [--output format]] buildfile destination",
 		Short: "Generates a fotoDen folder/album from a valid YAML file",
-		Args:  cobra.ExactArgs(2),
+		Long: `Generates a fotoDen folder/album from a valid YAML file.
+
+Building is idempotent: anything in the YAML file that already exists in the
+destination is updated to match the YAML file instead of being created again,
+and the items of every album are synced with the images declared in it.`,
+		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			b := new(tool.BuildFile)
 			err := b.OpenBuildYAML(args[0])
@@ -21,12 +43,67 @@ var (
 				return err
 			}
 
-			err = b.Build(args[1])
+			if dryRun {
+				return printPlan(b, args[1])
+			}
+
+			// images that failed to build don't stop anything else,
+			// and are reported once everything else is done
+			var batchErr generator.BatchError
+			err = currentSite.Build(b, args[1])
+			if err != nil && !errors.As(err, &batchErr) {
+				return err
+			}
+
+			if prune {
+				pruneErr := currentSite.Prune(b, args[1])
+				if pruneErr != nil {
+					return pruneErr
+				}
+			}
+
+			if !watch {
+				return err
+			}
+
+			if err != nil {
+				// failed images are picked up again once they change
+				log.Println(err)
+			}
+
+			w := currentSite.NewWatcher()
+			w.Debounce = debounce
+			err = w.AddBuild(b, args[1])
 			if err != nil {
 				return err
 			}
 
+			fmt.Println("Watching for changes, press Ctrl+C to stop.")
+			w.Watch(nil)
+
 			return nil
 		},
 	}
 )
+
+// printPlan prints the plan of building a build file into the given folder,
+// in the format given by --output.
+func printPlan(b *tool.BuildFile, folder string) error {
+	if output != "text" && output != "json" {
+		return fmt.Errorf("unknown output format: %s", output)
+	}
+
+	p, err := currentSite.PlanBuild(b, folder, prune)
+	if err != nil {
+		return err
+	}
+
+	if output == "json" {
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		return e.Encode(p)
+	}
+
+	p.Print(os.Stdout)
+	return nil
+}
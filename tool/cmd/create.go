@@ -14,14 +14,14 @@ func init() {
 	genCmd.AddCommand(genFolderCmd)
 	genFolderCmd.Flags().StringVar(&folderMeta.Name, "name", "", "name for fotoDen folders/albums")
 	genFolderCmd.Flags().StringVar(&folderMeta.Desc, "desc", "", "description for fotoDen folders/albums")
-	genFolderCmd.Flags().StringVar(&tool.ThumbSrc, "thumb", "", "location of the thumbnail for the folder/album")
+	genFolderCmd.Flags().StringVar(&folderMeta.Thumb, "thumb", "", "location of the thumbnail for the folder/album")
 	genFolderCmd.Flags().BoolVar(&opts.Static, "static", false, "toggle more static generation of websites in fotoDen folders/albums")
 
 	genCmd.AddCommand(genAlbumCmd)
 	genAlbumCmd.Flags().StringVar(&opts.Source, "source", "", "source for fotoDen images")
 	genAlbumCmd.Flags().StringVar(&folderMeta.Name, "name", "", "name for fotoDen folders/albums")
 	genAlbumCmd.Flags().StringVar(&folderMeta.Desc, "desc", "", "description for fotoDen folders/albums")
-	genAlbumCmd.Flags().StringVar(&tool.ThumbSrc, "thumb", "", "location of the thumbnail for the folder/album")
+	genAlbumCmd.Flags().StringVar(&folderMeta.Thumb, "thumb", "", "location of the thumbnail for the folder/album")
 	genAlbumCmd.Flags().BoolVar(&opts.Copy, "copy", false, "toggle copying of images from source to fotoDen albums")
 	genAlbumCmd.Flags().BoolVar(&opts.Gensizes, "gensizes", true, "toggle generation of all image sizes from source to fotoDen albums")
 	genAlbumCmd.Flags().BoolVar(&opts.Sort, "sort", true, "toggle sorting of all images in fotoDen albums by name")
@@ -59,18 +59,10 @@ var (
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if folderMeta.Name == "" {
 				folderMeta.Name = path.Base(wd)
-				err := tool.GenerateFolder(folderMeta, args[0], opts)
-				if err != nil {
-					return err
-				}
-			} else {
-				err := tool.GenerateFolder(folderMeta, args[0], opts)
-				if err != nil {
-					return err
-				}
 			}
 
-			return nil
+			currentSite.Options.Static = opts.Static
+			return currentSite.CreateFolder(folderMeta, args[0])
 		},
 	}
 	genAlbumCmd = &cobra.Command{
@@ -78,23 +70,13 @@ var (
 		Short: "Creates a fotoDen album",
 		Args:  cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			opts.ImageGen = true
 			opts.Source = args[0]
 
 			if folderMeta.Name == "" {
 				folderMeta.Name = path.Base(wd)
-				err := tool.GenerateFolder(folderMeta, args[1], opts)
-				if err != nil {
-					return err
-				}
-			} else {
-				err := tool.GenerateFolder(folderMeta, args[1], opts)
-				if err != nil {
-					return err
-				}
 			}
 
-			return nil
+			return currentSite.CreateAlbum(folderMeta, args[1], opts)
 		},
 	}
 	genPageCmd = &cobra.Command{
@@ -102,12 +84,7 @@ var (
 		Short: "Creates a webpage using a fotoDen template and Markdown (incomplete)",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			err := tool.GeneratePage(args[0], t)
-			if err != nil {
-				return err
-			}
-
-			return nil
+			return currentSite.GeneratePage(args[0], t)
 		},
 	}
 )
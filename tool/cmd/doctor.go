@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/vulppine/fotoDen/tool"
+)
+
+func init() {
+	rootCmd.AddCommand(doctorCmd)
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "fix every issue that can be fixed")
+	doctorCmd.Flags().StringVarP(&doctorOutput, "output", "o", "text", "the format to print issues in (text or json)")
+}
+
+var (
+	doctorFix    bool
+	doctorOutput string
+	doctorCmd    = &cobra.Command{
+		Use:   "doctor [--fix] [--output format] [folder]",
+		Short: "Checks the folders and albums of a site for inconsistencies",
+		Long: `Checks every folder and album in the given folder of the current site
+(or the root of the site, if no folder is given) for inconsistencies, such as
+items without sizes, subfolders that no longer exist, missing thumbnails,
+orphaned files, and item counts that do not match the items of an album.
+
+With --fix, missing sizes and metadata are generated again from the source of
+their album, counts and subfolders are rewritten, and orphaned files are removed.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if doctorOutput != "text" && doctorOutput != "json" {
+				return fmt.Errorf("unknown output format: %s", doctorOutput)
+			}
+
+			folder := currentSite.RootLocation
+			if len(args) > 0 {
+				folder = args[0]
+			}
+
+			if folder == "" {
+				return fmt.Errorf("you need to use this in conjunction with a valid fotoDen site, or give a folder")
+			}
+
+			issues, err := currentSite.Doctor(folder, doctorFix)
+			if err != nil {
+				return err
+			}
+
+			if doctorOutput == "json" {
+				e := json.NewEncoder(os.Stdout)
+				e.SetIndent("", "  ")
+				err = e.Encode(issues)
+				if err != nil {
+					return err
+				}
+			} else {
+				tool.PrintIssues(os.Stdout, issues)
+			}
+
+			left := 0
+			for _, i := range issues {
+				if !i.Fixed {
+					left++
+				}
+			}
+
+			if left > 0 {
+				return fmt.Errorf("%d issue(s) left", left)
+			}
+
+			return nil
+		},
+	}
+)
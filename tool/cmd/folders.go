@@ -11,11 +11,23 @@ func init() {
 
 	albumCmd.AddCommand(albumAddCmd)
 	albumAddCmd.Flags().BoolVarP(&sortf, "sort", "s", true, "sorts an album's images after adding")
-	albumAddCmd.Flags().BoolVar(&tool.Genoptions.Copy, "copy", false, "toggle copying of images from source to fotoDen albums")
-	albumAddCmd.Flags().BoolVar(&tool.Genoptions.Gensizes, "gensizes", true, "toggle generation of all image sizes from source to fotoDen albums")
-	albumAddCmd.Flags().BoolVar(&tool.Genoptions.Meta, "meta", true, "toggle generation of metadata templates in fotoDen albums")
+	albumAddCmd.Flags().BoolVar(&addOpts.Copy, "copy", false, "toggle copying of images from source to fotoDen albums")
+	albumAddCmd.Flags().BoolVar(&addOpts.Gensizes, "gensizes", true, "toggle generation of all image sizes from source to fotoDen albums")
+	albumAddCmd.Flags().BoolVar(&addOpts.Meta, "meta", true, "toggle generation of metadata templates in fotoDen albums")
 
 	albumCmd.AddCommand(albumDelCmd)
+	albumDelCmd.Flags().BoolVar(&keepFiles, "keep-files", false, "only remove images from the album's items, keeping their sizes, sources and metadata")
+
+	albumCmd.AddCommand(albumSyncCmd)
+	albumSyncCmd.Flags().BoolVarP(&syncOpts.Sort, "sort", "s", true, "sorts an album's images after syncing")
+	albumSyncCmd.Flags().BoolVar(&syncOpts.Copy, "copy", false, "toggle copying of images from source to fotoDen albums")
+	albumSyncCmd.Flags().BoolVar(&syncOpts.Gensizes, "gensizes", true, "toggle generation of all image sizes from source to fotoDen albums")
+	albumSyncCmd.Flags().BoolVar(&syncOpts.Meta, "meta", true, "toggle generation of metadata templates in fotoDen albums")
+
+	albumCmd.AddCommand(albumMergeCmd)
+
+	folderCmd.AddCommand(folderMoveCmd)
+	folderCmd.AddCommand(folderRenameCmd)
 
 	albumCmd.AddCommand(updateCmd)
 	folderCmd.AddCommand(updateCmd)
@@ -29,6 +41,9 @@ func init() {
 
 var (
 	sortf     bool
+	keepFiles bool
+	addOpts   tool.GeneratorOptions
+	syncOpts  tool.GeneratorOptions
 	folderCmd = &cobra.Command{
 		Use:   "folder",
 		Short: "Works with fotoDen folders",
@@ -44,21 +59,65 @@ var (
 		Args:  cobra.MinimumNArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if sortf {
-				err := tool.InsertImage(args[0], "sort", tool.Genoptions, args[1:]...)
-				return err
+				return currentSite.AddImages(args[0], "sort", addOpts, args[1:]...)
 			}
 
-			err := tool.InsertImage(args[0], "append", tool.Genoptions, args[1:]...)
-			return err
+			return currentSite.AddImages(args[0], "append", addOpts, args[1:]...)
 		},
 	}
 	albumDelCmd = &cobra.Command{
-		Use:   "delete album_name images",
-		Short: "Deletes images from albums.",
+		Use:   "delete [--keep-files] album_name images",
+		Short: "Deletes images from albums, alongside every file generated from them.",
 		Args:  cobra.MinimumNArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			err := tool.DeleteImage(args[0], args[1:]...)
-			return err
+			if keepFiles {
+				return currentSite.DeleteItems(args[0], args[1:]...)
+			}
+
+			return currentSite.DeleteImages(args[0], args[1:]...)
+		},
+	}
+	albumMergeCmd = &cobra.Command{
+		Use:   "merge source_album destination_album",
+		Short: "Merges an album into another album, and removes it.",
+		Long: `Merges an album into another album, and removes it.
+
+Every image in the source album is moved into the destination album, alongside
+its sizes, copied source and metadata. Images with the same name as an image in
+the destination album are renamed by adding a number to their name. Folders and
+albums inside the source album are moved into the destination album.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return currentSite.MergeAlbums(args[0], args[1])
+		},
+	}
+	folderMoveCmd = &cobra.Command{
+		Use:   "move folder destination",
+		Short: "Moves a folder/album into another location.",
+		Long: `Moves a folder/album into another location. The destination must not exist yet.
+
+The folder/album is removed from the folder it was in, added to the folder it
+is moved into, and its pages are generated again.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return currentSite.MoveFolder(args[0], args[1])
+		},
+	}
+	folderRenameCmd = &cobra.Command{
+		Use:   "rename folder new_name",
+		Short: "Renames the directory of a folder/album.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return currentSite.RenameFolder(args[0], args[1])
+		},
+	}
+	albumSyncCmd = &cobra.Command{
+		Use:   "sync album_name source",
+		Short: "Syncs an album with a source folder, only regenerating images that were added or changed.",
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			syncOpts.Source = args[1]
+			return currentSite.UpdateImages(args[0], syncOpts)
 		},
 	}
 )
@@ -78,8 +137,7 @@ var (
 		Short: "updates fotoDen album information",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			err := tool.UpdateFolder(args[0], nameFlag, descFlag)
-			return err
+			return currentSite.UpdateFolder(args[0], nameFlag, descFlag)
 		},
 	}
 	updateThumbCmd = &cobra.Command{
@@ -87,8 +145,7 @@ var (
 		Short: "updates a fotoDen folder/album thumbnail",
 		Args:  cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			err := tool.UpdateFolderThumbnail(args[0], args[1])
-			return err
+			return currentSite.UpdateFolderThumbnail(args[0], args[1])
 		},
 	}
 )
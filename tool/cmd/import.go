@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/vulppine/fotoDen/tool"
+)
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.AddCommand(importTreeCmd)
+	importTreeCmd.Flags().BoolVar(&importOpts.Copy, "copy", false, "toggle copying of images from source to fotoDen albums")
+	importTreeCmd.Flags().BoolVar(&importOpts.Gensizes, "gensizes", true, "toggle generation of all image sizes from source to fotoDen albums")
+	importTreeCmd.Flags().BoolVar(&importOpts.Sort, "sort", true, "toggle sorting of all images in fotoDen albums by name")
+	importTreeCmd.Flags().BoolVar(&importOpts.Meta, "meta", true, "toggle generation of metadata templates in fotoDen albums")
+	importTreeCmd.Flags().StringVar(&importYAML, "buildfile", "", "where to write the build file to (defaults to the name of the destination, with .yaml at the end, in the working directory)")
+	importTreeCmd.Flags().BoolVar(&importOnly, "no-build", false, "only write the build file, without building it")
+}
+
+var (
+	importOpts tool.BuildImageOptions
+	importYAML string
+	importOnly bool
+	importCmd  = &cobra.Command{
+		Use:   "import",
+		Short: "Imports things into fotoDen websites",
+	}
+	importTreeCmd = &cobra.Command{
+		Use:   "tree [options] source destination",
+		Short: "Mirrors a directory tree of images into a fotoDen folder",
+		Long: `Mirrors a directory tree of images into a fotoDen folder. Every directory
+holding images becomes an album, and every directory holding only directories
+becomes a folder, each named after the directory it comes from.
+
+The build file for the imported tree is written out into the working directory
+(or to --buildfile), so that it can be edited and built again with fotoDen build.
+It is kept outside of the website, as it holds the paths of your source images.`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dest := filepath.Clean(args[1])
+			if importYAML == "" {
+				importYAML = filepath.Base(dest) + ".yaml"
+			}
+
+			b, err := currentSite.ImportTree(args[0], importOpts)
+			if err != nil {
+				return err
+			}
+
+			if b.Name != filepath.Base(dest) {
+				b.Dir = filepath.Base(dest)
+			}
+
+			err = b.WriteBuildYAML(importYAML)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Build file written to %s - build it again with: fotoDen build %s %s\n", importYAML, importYAML, filepath.Dir(dest))
+			if importOnly {
+				return nil
+			}
+
+			return currentSite.Build(b, filepath.Dir(dest))
+		},
+	}
+)
@@ -12,7 +12,7 @@ func init() {
 	// initCmd.AddCommand(initConfigCmd)
 	// initConfigCmd.Flags().StringVar(&tool.URLFlag, "url", "", "what URL to initialize fotoDen with")
 	initCmd.AddCommand(initSiteCmd)
-	initSiteCmd.Flags().StringVar(&tool.URLFlag, "source", "", "where fotoDen should obtain its images")
+	initSiteCmd.Flags().StringVar(&siteSource, "source", "", "where fotoDen should obtain its images")
 	initSiteCmd.Flags().StringVar(&websiteInit.URL, "url", "", "what URL to initialize fotoDen with")
 	initSiteCmd.Flags().StringVar(&websiteInit.Name, "name", "", "what name a site should have (with init site)")
 	initSiteCmd.Flags().StringVar(&websiteInit.Theme, "theme", "", "what theme a site should use")
@@ -39,13 +39,15 @@ var (
 	}
 	*/
 	websiteInit tool.WebsiteConfig
+	siteSource  string
 	initSiteCmd = &cobra.Command{
 		Use:   "site [--name] destination",
 		Short: "Initializes a fotoDen website in the given directory",
 		Args:  cobra.ExactArgs(1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			err := tool.InitializefotoDenRoot(args[0], websiteInit)
-			return err
+			s := tool.NewSite(websiteInit)
+			s.Interactive = interactive
+			return s.Initialize(args[0], siteSource)
 		},
 	}
 	/* Deprecated in favor of zipped/embed theme files
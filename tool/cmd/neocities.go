@@ -3,96 +3,179 @@
 package cmd
 
 import (
+	"encoding/json"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"sort"
+	"text/tabwriter"
 
 	"github.com/spf13/cobra"
-	"github.com/vulppine/neocities-go"
+	"github.com/vulppine/fotoDen/generator"
+	"github.com/vulppine/fotoDen/tool"
 )
 
 func init() {
 	remoteCmd.AddCommand(NeoCitiesCMD)
-	NeoCitiesCMD.Flags().String("key", "", "The API key of your NeoCities site.")
-	NeoCitiesCMD.Flags().String("keyfile", "", "The API keyfile of your NeoCities site.")
+	neocitiesFlags(NeoCitiesCMD)
 	NeoCitiesCMD.AddCommand(UploadCMD)
 	NeoCitiesCMD.AddCommand(PushCMD)
 	NeoCitiesCMD.AddCommand(DeleteCMD)
+	NeoCitiesCMD.AddCommand(ListCMD)
 
-	UploadCMD.Flags().String("name", "", "The name you want for your uploaded file.")
+	UploadCMD.Flags().StringVar(&n, "name", "", "The name you want for your uploaded file (only if uploading a single file).")
+	PushCMD.Flags().BoolVar(&remoteDryRun, "dry-run", false, "print what would be uploaded and deleted, without pushing anything")
+	ListCMD.Flags().StringVarP(&listOutput, "output", "o", "text", "the format to print files in (text or json)")
+
+	neocitiesFlags(splitPushCmd)
+	remoteBackends["neocities"] = openNeoCities
+}
+
+// neocitiesFlags adds the flags that give the API key of a NeoCities website to a command.
+func neocitiesFlags(cmd *cobra.Command) {
+	cmd.PersistentFlags().StringVar(&s.Key, "key", "", "The API key of your NeoCities site.")
+	cmd.PersistentFlags().StringVar(&k, "keyfile", "", "The API keyfile of your NeoCities site.")
+}
+
+// openNeoCities returns the NeoCities website given by the API key in --key, the file
+// in --keyfile, or the current site (see tool.Site.NeoCitiesKey), in that order.
+func openNeoCities() (tool.Deployer, string, error) {
+	var err error
+	switch {
+	case s.Key != "":
+	case k != "":
+		s.Key, err = tool.ReadKeyFile(k)
+		if err != nil {
+			return nil, "", fmt.Errorf("keyfile read returned error: %w", err)
+		}
+	default:
+		s.Key, err = currentSite.NeoCitiesKey()
+		if err != nil {
+			return nil, "", err
+		}
+	}
+
+	return s, "neocities", nil
 }
 
 var (
-	err error
-	s = neocities.Site{}
+	s = &tool.NeoCitiesDeployer{}
 
-	k string
-	n string
+	k            string
+	n            string
+	listOutput   string
 	NeoCitiesCMD = &cobra.Command{
-		Use: "neocities",
+		Use:   "neocities",
 		Short: "Utilities for remotely uploading to NeoCities",
+		Long: `Utilities for remotely uploading to NeoCities.
+
+The API key of your NeoCities site is read from --key, the file in --keyfile,
+the NEOCITIES_API_KEY environment variable, or the neocities_key file in the
+directory of the current site in the fotoDen config directory, in that order.`,
 	}
 
 	UploadCMD = &cobra.Command{
-		Use: "upload file [-name string]",
-		Args: cobra.ExactArgs(1),
+		Use:   "upload file... [--name string]",
+		Short: "Uploads files into the root of a NeoCities site",
+		Args:  cobra.MinimumNArgs(1),
 		RunE: func(c *cobra.Command, args []string) error {
-			if k != "" {
-				s.Key, err = neocities.ReadFile(k)
-				if err != nil {
-					return fmt.Errorf("keyfile read returned error")
-				}
+			if n != "" && len(args) > 1 {
+				return fmt.Errorf("--name can only be used when uploading a single file")
 			}
 
-			if n == "" {
-				n = filepath.Base(args[0])
-			}
-
-			err := s.UploadFile(args[0], n, nil)
+			_, _, err := openNeoCities()
 			if err != nil {
 				return err
 			}
 
-			return nil
+			var batchErr generator.BatchError
+			for _, f := range args {
+				name := n
+				if name == "" {
+					name = filepath.Base(f)
+				}
+
+				data, err := ioutil.ReadFile(f)
+				if err == nil {
+					err = s.Upload(name, data)
+				}
+
+				if err != nil {
+					batchErr = batchErr.Append(&generator.FileError{File: f, Op: "upload", Err: err})
+				}
+			}
+
+			return batchErr.ErrorOrNil()
 		},
 	}
 
 	PushCMD = &cobra.Command{
-		Use: "push directory",
-		Args: cobra.ExactArgs(1),
+		Use:   "push [directory]",
+		Short: "Uploads the changes in a site into a NeoCities site",
+		Long: `Uploads every new or changed file in a site into a NeoCities site, and deletes
+files from it that are no longer in the site. If no directory is given, the root
+of the current site is used.`,
+		Args: cobra.MaximumNArgs(1),
 		RunE: func(c *cobra.Command, args []string) error {
-			if k != "" {
-				s.Key, err = neocities.ReadFile(k)
-				if err != nil {
-					return fmt.Errorf("keyfile read returned error")
-				}
-			}
+			return pushRemote(openNeoCities, args)
+		},
+	}
 
-			err := s.Push(args[0], nil)
+	DeleteCMD = &cobra.Command{
+		Use:   "delete files",
+		Short: "Deletes files from a NeoCities site",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			_, _, err := openNeoCities()
 			if err != nil {
 				return err
 			}
 
-			return nil
+			var batchErr generator.BatchError
+			for _, f := range args {
+				err = s.Delete(f)
+				if err != nil {
+					batchErr = batchErr.Append(&generator.FileError{File: f, Op: "delete", Err: err})
+				}
+			}
+
+			return batchErr.ErrorOrNil()
 		},
 	}
 
-	DeleteCMD = &cobra.Command{
-		Use: "delete files",
-		Args: cobra.MinimumNArgs(1),
+	ListCMD = &cobra.Command{
+		Use:   "list",
+		Short: "Lists every file in a NeoCities site, with its size and SHA-1 hash",
+		Args:  cobra.NoArgs,
 		RunE: func(c *cobra.Command, args []string) error {
-			if k != "" {
-				s.Key, err = neocities.ReadFile(k)
-				if err != nil {
-					return fmt.Errorf("keyfile read returned error")
-				}
+			_, _, err := openNeoCities()
+			if err != nil {
+				return err
 			}
 
-			err := s.DeleteFiles(nil, args...)
+			files, err := s.List()
 			if err != nil {
-				return nil
+				return err
 			}
 
-			return nil
+			sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+
+			switch listOutput {
+			case "text":
+				w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+				for _, f := range files {
+					fmt.Fprintf(w, "%s\t%d\t%s\n", f.Name, f.Size, f.Hash)
+				}
+
+				return w.Flush()
+			case "json":
+				e := json.NewEncoder(os.Stdout)
+				e.SetIndent("", "  ")
+				return e.Encode(files)
+			default:
+				return fmt.Errorf("unknown output format: %s", listOutput)
+			}
 		},
 	}
 )
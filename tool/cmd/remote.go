@@ -3,7 +3,12 @@
 package cmd
 
 import (
+	"fmt"
+	"io"
+	"os"
+
 	"github.com/spf13/cobra"
+	"github.com/vulppine/fotoDen/tool"
 )
 
 func init() {
@@ -16,3 +21,61 @@ var (
 		Short: "Utilities for remotely uploading to a webhost",
 	}
 )
+
+var (
+	remoteDryRun bool
+
+	// remoteBackends are the remotes that a site can be pushed into, by their name.
+	// Each returns the remote configured by its flags, and the name of its manifest.
+	remoteBackends = make(map[string]func() (tool.Deployer, string, error))
+)
+
+// pushRemote opens a remote and pushes a site into it (see pushSite),
+// using the site root in args if there is one.
+func pushRemote(open func() (tool.Deployer, string, error), args []string) error {
+	d, remote, err := open()
+	if err != nil {
+		return err
+	}
+
+	if c, ok := d.(io.Closer); ok {
+		defer c.Close()
+	}
+
+	root := ""
+	if len(args) > 0 {
+		root = args[0]
+	}
+
+	return pushSite(d, root, remote)
+}
+
+// siteRoot returns root, or the root of the current site if root is empty.
+func siteRoot(root string) (string, error) {
+	if root == "" {
+		root = currentSite.RootLocation
+	}
+
+	if root == "" {
+		return "", fmt.Errorf("you need to use this in conjunction with a valid fotoDen site, or give a directory")
+	}
+
+	return root, nil
+}
+
+// pushSite pushes the website in root (or the root of the current site, if root
+// is empty) into a remote, and prints what was changed. If --dry-run is set,
+// the changes are only printed.
+func pushSite(d tool.Deployer, root string, remote string) error {
+	root, err := siteRoot(root)
+	if err != nil {
+		return err
+	}
+
+	changes, err := currentSite.Push(d, root, remote, remoteDryRun)
+	if changes != nil {
+		tool.PrintChangeset(os.Stdout, changes)
+	}
+
+	return err
+}
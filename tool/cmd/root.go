@@ -11,6 +11,9 @@ import (
 	"github.com/vulppine/fotoDen/tool"
 )
 
+// Execute runs fotoDen's command line interface. If the command fails,
+// its error is printed (for batch operations, this is a summary of every
+// file that failed) and returned.
 func Execute() error {
 	return rootCmd.Execute()
 }
@@ -28,14 +31,22 @@ func debug(input interface{}) {
 }
 
 var (
-	d         = rootCmd.PersistentFlags().Bool("debug", false, "Prints debug information to console.")
-	v         = rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Prints verbose information made by fotoDen")
-	configDir string
-	configSrc generator.Config
-	site      string
-	rootCmd   = &cobra.Command{
+	d           = rootCmd.PersistentFlags().Bool("debug", false, "Prints debug information to console.")
+	v           = rootCmd.PersistentFlags().BoolP("verbose", "v", false, "Prints verbose information made by fotoDen")
+	configDir   string
+	jobs        int
+	interactive bool
+	configSrc   generator.Config
+	site        string
+	currentSite *tool.Site // the site that every command works on, opened in setRootFlags
+	rootCmd     = &cobra.Command{
 		Use:   "fotoDen { init | generate | update } args [--config string] [--verbose | -v] [--interactive | -i]",
 		Short: "A static photo gallery generator",
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			// the arguments are valid by now, so any error from
+			// this point on has nothing to do with usage
+			cmd.SilenceUsage = true
+		},
 	}
 )
 
@@ -75,21 +86,36 @@ func setRootFlags() {
 
 	if site != "___NOSITE" {
 		verbose(filepath.Join(configDir, "sites", site, "config.json"))
-		s := new(tool.WebsiteConfig)
-		err := generator.ReadJSON(filepath.Join(generator.RootConfigDir, "sites", site, "config.json"), s)
+		s, err := tool.OpenSite(site)
 		if err != nil {
 			log.Fatal(err)
 			os.Exit(1)
 		}
 
-		tool.CurrentConfig = s
-		generator.CurrentConfig = s.GeneratorConfig
+		currentSite = s
+	} else {
+		currentSite = tool.NewSite(tool.WebsiteConfig{})
+	}
+
+	currentSite.Interactive = interactive
+
+	err := generator.SetImageBackend(currentSite.GeneratorConfig.ImageBackend)
+	if err != nil {
+		log.Fatal(err)
+		os.Exit(1)
 	}
+
+	if jobs == 0 {
+		jobs = currentSite.GeneratorConfig.Jobs
+	}
+
+	generator.SetJobs(jobs)
 }
 
 func init() {
 	cobra.OnInitialize(setRootFlags)
-	rootCmd.PersistentFlags().BoolVarP(&tool.WizardFlag, "interactive", "i", false, "Allows fotoDen to display interactive prompts")
+	rootCmd.PersistentFlags().BoolVarP(&interactive, "interactive", "i", false, "Allows fotoDen to display interactive prompts")
 	rootCmd.PersistentFlags().StringVar(&configDir, "config-dir", "", "The config directory to use for fotoDen")
 	rootCmd.PersistentFlags().StringVar(&site, "site", "", "The website that fotoDen should focus on.")
+	rootCmd.PersistentFlags().IntVarP(&jobs, "jobs", "j", 0, "How many images fotoDen should process at once. Defaults to the site config, or the amount of CPUs.")
 }
@@ -0,0 +1,79 @@
+// +build all remote
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/vulppine/fotoDen/tool"
+)
+
+func init() {
+	remoteCmd.AddCommand(s3Cmd)
+	s3Cmd.AddCommand(s3PushCmd)
+	s3Flags(s3PushCmd)
+	s3PushCmd.Flags().BoolVar(&remoteDryRun, "dry-run", false, "print what would be uploaded and deleted, without pushing anything")
+
+	s3Flags(splitPushCmd)
+	remoteBackends["s3"] = openS3
+}
+
+// s3Flags adds the flags that configure the S3 remote to a command.
+func s3Flags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&s3Remote.Endpoint, "endpoint", "https://s3.amazonaws.com", "the URL of the S3 API (e.g., http://localhost:9000 for a local MinIO)")
+	cmd.Flags().StringVar(&s3Remote.Region, "region", "", "the region of the bucket (default $AWS_REGION, or us-east-1)")
+	cmd.Flags().StringVar(&s3Remote.Bucket, "bucket", "", "the bucket to upload the site into")
+	cmd.Flags().StringVar(&s3Remote.Prefix, "prefix", "", "where the site is in the bucket, if not its root")
+	cmd.Flags().BoolVar(&s3Remote.PathStyle, "path-style", false, "put the bucket in the path of requests rather than the host (needed for MinIO)")
+}
+
+// openS3 returns the S3 remote configured by its flags, and the name of its manifest.
+func openS3() (tool.Deployer, string, error) {
+	if s3Remote.Bucket == "" {
+		return nil, "", fmt.Errorf("--bucket must be given")
+	}
+
+	s3Remote.AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	s3Remote.SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if s3Remote.AccessKey == "" || s3Remote.SecretKey == "" {
+		return nil, "", fmt.Errorf("AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+
+	if s3Remote.Region == "" {
+		s3Remote.Region = os.Getenv("AWS_REGION")
+	}
+
+	if s3Remote.Region == "" {
+		s3Remote.Region = "us-east-1"
+	}
+
+	return s3Remote, "s3-" + s3Remote.Bucket + "-" + s3Remote.Prefix, nil
+}
+
+var (
+	s3Remote = new(tool.S3Deployer)
+	s3Cmd    = &cobra.Command{
+		Use:   "s3",
+		Short: "Utilities for remotely uploading to S3-compatible storage",
+		Long: `Utilities for remotely uploading to S3-compatible storage, such as Amazon S3 or MinIO.
+
+Credentials are read from the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+environment variables.`,
+	}
+	s3PushCmd = &cobra.Command{
+		Use:   "push [site_root]",
+		Short: "Uploads the changes in a site into a bucket",
+		Long: `Uploads every new or changed file in a site into a bucket, and deletes files
+from the bucket that are no longer in the site. If no directory is given, the
+root of the current site is used.
+
+What was pushed is kept in a manifest in the fotoDen config directory. If the
+manifest does not exist yet, the bucket is listed instead.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pushRemote(openS3, args)
+		},
+	}
+)
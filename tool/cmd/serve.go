@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "localhost:8080", "The address to serve the website on")
+	serveCmd.Flags().StringVar(&serveBaseURL, "base-url", "", "The base URL the website was generated with (default from the site configuration, or the root web page)")
+}
+
+var (
+	serveAddr    string
+	serveBaseURL string
+	serveCmd     = &cobra.Command{
+		Use:   "serve [--addr address] [site_root]",
+		Short: "Serves a fotoDen website locally, reloading pages when it changes",
+		Long: `Serves a fotoDen website locally over HTTP, as if it were hosted at the
+address it is being served on. Pages are reloaded automatically whenever
+folder info, items info, image metadata, web pages or theme files change.
+
+Links to the base URL the website was generated with are rewritten to point
+at the local server. The base URL is read from --base-url, the configuration
+of the current site, or the root web page of the website, in that order.
+
+If no site root is given, the root of the current site is served.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := currentSite.RootLocation
+			if len(args) > 0 {
+				root = args[0]
+			}
+
+			if root == "" {
+				return fmt.Errorf("no site root was given, and there is no current site")
+			}
+
+			return currentSite.Serve(root, serveAddr, serveBaseURL)
+		},
+	}
+)
@@ -0,0 +1,127 @@
+// +build all remote
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/vulppine/fotoDen/tool"
+)
+
+func init() {
+	remoteCmd.AddCommand(sftpCmd)
+	sftpCmd.AddCommand(sftpPushCmd)
+	sftpFlags(sftpPushCmd)
+	sftpPushCmd.Flags().BoolVar(&remoteDryRun, "dry-run", false, "print what would be uploaded and deleted, without pushing anything")
+
+	sftpFlags(splitPushCmd)
+	remoteBackends["sftp"] = openSFTP
+}
+
+// sftpFlags adds the flags that configure the SFTP remote to a command.
+func sftpFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&sftpRemote.Host, "host", "", "the host of the SSH server, and its port if it is not 22 (default from the site configuration)")
+	cmd.Flags().StringVar(&sftpRemote.User, "user", "", "the user to log in as (default from the site configuration, or $USER)")
+	cmd.Flags().StringVar(&sftpRemote.BaseDir, "base-dir", "", "the directory to upload the site into (default from the site configuration)")
+	cmd.Flags().StringVar(&sftpIdentity, "identity", "", "the private key to log in with, alongside the keys in the SSH agent")
+	cmd.Flags().StringVar(&sftpKnownHosts, "known-hosts", "", "the known_hosts file to check the key of the server against (default ~/.ssh/known_hosts)")
+	cmd.Flags().BoolVar(&sftpSave, "save", false, "save the host, user and base directory into the site configuration")
+}
+
+// openSFTP connects to the SFTP remote configured by its flags,
+// and returns it with the name of its manifest.
+func openSFTP() (tool.Deployer, string, error) {
+	config, err := sftpConfig()
+	if err != nil {
+		return nil, "", err
+	}
+
+	client, err := tool.SSHClientConfig(config.User, sftpIdentity, sftpKnownHosts)
+	if err != nil {
+		return nil, "", err
+	}
+
+	d, err := tool.DialSFTP(config.Host, client, config.BaseDir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return d, "sftp-" + config.Host + "-" + config.BaseDir, nil
+}
+
+var (
+	sftpRemote     tool.SFTPConfig
+	sftpIdentity   string
+	sftpKnownHosts string
+	sftpSave       bool
+	sftpCmd        = &cobra.Command{
+		Use:   "sftp",
+		Short: "Utilities for remotely uploading to a server over SFTP",
+		Long: `Utilities for remotely uploading to a server over SFTP.
+
+You can log in with a private key (--identity), or with any key in the SSH agent
+at $SSH_AUTH_SOCK. The key of the server must be in your known_hosts file.`,
+	}
+	sftpPushCmd = &cobra.Command{
+		Use:   "push [site_root]",
+		Short: "Uploads the changes in a site into a directory on a server",
+		Long: `Uploads every new or changed file in a site into a directory on a server, and
+deletes files from it that are no longer in the site. If no directory is given,
+the root of the current site is used.
+
+HTML and JSON files are replaced all at once, so that visitors never see a page
+that is only half-uploaded. What was pushed is kept in a manifest in the fotoDen
+config directory - if the manifest does not exist yet, every file is uploaded.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return pushRemote(openSFTP, args)
+		},
+	}
+)
+
+// sftpConfig returns the SFTP configuration of the current site, with every flag
+// given replacing what is in it. If --save is set, the configuration is saved.
+func sftpConfig() (tool.SFTPConfig, error) {
+	config := currentSite.SFTP
+	if sftpRemote.Host != "" {
+		config.Host = sftpRemote.Host
+	}
+
+	if sftpRemote.User != "" {
+		config.User = sftpRemote.User
+	}
+
+	if sftpRemote.BaseDir != "" {
+		config.BaseDir = sftpRemote.BaseDir
+	}
+
+	if config.Host == "" || config.BaseDir == "" {
+		return config, fmt.Errorf("--host and --base-dir must be given, or set in the site configuration")
+	}
+
+	if sftpSave {
+		currentSite.SFTP = config
+		err := currentSite.SaveConfig()
+		if err != nil {
+			return config, err
+		}
+	}
+
+	if config.User == "" {
+		config.User = os.Getenv("USER")
+	}
+
+	if sftpKnownHosts == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return config, err
+		}
+
+		sftpKnownHosts = filepath.Join(home, ".ssh", "known_hosts")
+	}
+
+	return config, nil
+}
@@ -0,0 +1,120 @@
+// +build all remote
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vulppine/fotoDen/tool"
+)
+
+func init() {
+	remoteCmd.AddCommand(splitCmd)
+	splitCmd.AddCommand(splitPushCmd)
+	splitPushCmd.Flags().StringVar(&splitPages, "pages", "", "the remote to push pages, information and theme files into")
+	splitPushCmd.Flags().StringVar(&splitStorage, "storage", "", "the remote to push images into")
+	splitPushCmd.Flags().StringVar(&splitStorageURL, "storage-url", "", "the URL that images are served from (default the storageURL in the site's config.json)")
+	splitPushCmd.Flags().StringSliceVar(&splitLocalSizes, "local-sizes", nil, "image sizes to keep with the pages, rather than in storage")
+	splitPushCmd.Flags().BoolVar(&remoteDryRun, "dry-run", false, "print what would be uploaded and deleted, without pushing anything")
+	splitPushCmd.MarkFlagRequired("pages")
+	splitPushCmd.MarkFlagRequired("storage")
+}
+
+var (
+	splitPages      string
+	splitStorage    string
+	splitStorageURL string
+	splitLocalSizes []string
+	splitCmd        = &cobra.Command{
+		Use:   "split",
+		Short: "Utilities for uploading images and pages into different remotes",
+	}
+	splitPushCmd = &cobra.Command{
+		Use:   "push --pages remote --storage remote [site_root]",
+		Short: "Uploads the images of a site into one remote, and everything else into another",
+		Long: `Uploads the images of a site into one remote (e.g., S3-compatible storage),
+and its pages, information and theme files into another (e.g., a web host over
+SFTP). If no directory is given, the root of the current site is used. Both
+remotes are configured with the same flags as their own push commands, so they
+must be different kinds of remote.
+
+The config.json that is deployed has its storageURL set to --storage-url, and
+every image size that is not in --local-sizes is read from storage. Images are
+stored where fotoDen.js looks for them: under the name of the directory that the
+site is in (from the site's base URL), followed by the path of their album.
+Image metadata is always kept with the pages.
+
+Images are pushed first, and are checked to be in storage once everything
+is pushed. What was pushed is kept in manifests of its own, apart from the
+manifests of pushing the whole site into the same remotes.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			root := ""
+			if len(args) > 0 {
+				root = args[0]
+			}
+
+			root, err := siteRoot(root)
+			if err != nil {
+				return err
+			}
+
+			if splitPages == splitStorage {
+				return fmt.Errorf("--pages and --storage must be different kinds of remote")
+			}
+
+			split := tool.SplitDeploy{StorageURL: splitStorageURL, LocalSizes: splitLocalSizes}
+			split.Pages, split.PagesRemote, err = openBackend(splitPages)
+			if err != nil {
+				return err
+			}
+
+			if c, ok := split.Pages.(io.Closer); ok {
+				defer c.Close()
+			}
+
+			split.Storage, split.StorageRemote, err = openBackend(splitStorage)
+			if err != nil {
+				return err
+			}
+
+			if c, ok := split.Storage.(io.Closer); ok {
+				defer c.Close()
+			}
+
+			pages, storage, err := currentSite.PushSplit(root, split, remoteDryRun)
+			if storage != nil {
+				fmt.Println("storage (" + splitStorage + "):")
+				tool.PrintChangeset(os.Stdout, storage)
+			}
+
+			if pages != nil {
+				fmt.Println("pages (" + splitPages + "):")
+				tool.PrintChangeset(os.Stdout, pages)
+			}
+
+			return err
+		},
+	}
+)
+
+// openBackend opens the remote with the given name (see remoteBackends).
+func openBackend(name string) (tool.Deployer, string, error) {
+	open, ok := remoteBackends[name]
+	if !ok {
+		names := make([]string, 0, len(remoteBackends))
+		for n := range remoteBackends {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		return nil, "", fmt.Errorf("unknown remote %s (must be one of: %s)", name, strings.Join(names, ", "))
+	}
+
+	return open()
+}
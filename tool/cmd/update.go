@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"github.com/spf13/cobra"
-	"github.com/vulppine/fotoDen/tool"
 )
 
 func init() {
@@ -10,12 +9,13 @@ func init() {
 
 	updCmd.AddCommand(updFolderCmd)
 	updCmd.AddCommand(updWebCmd)
-	updFolderCmd.Flags().BoolVarP(&tool.Recurse, "recurse", "r", true, "toggles recursing through folders")
-	updWebCmd.Flags().BoolVarP(&tool.Recurse, "recurse", "r", true, "toggles recursing through folders")
+	updFolderCmd.Flags().BoolVarP(&recurse, "recurse", "r", true, "toggles recursing through folders")
+	updWebCmd.Flags().BoolVarP(&recurse, "recurse", "r", true, "toggles recursing through folders")
 }
 
 var (
-	updCmd = &cobra.Command{
+	recurse bool
+	updCmd  = &cobra.Command{
 		Use:   "update { folder | web } folder",
 		Short: "Updates various fotoDen resources",
 	}
@@ -24,14 +24,14 @@ var (
 		Args:  cobra.ExactArgs(1),
 		Short: "Updates fotoDen folder subdirectories",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if tool.Recurse {
-				err := tool.RecursiveVisit(args[0], tool.UpdateFolderSubdirectories)
+			if recurse {
+				err := currentSite.RecursiveVisit(args[0], currentSite.UpdateFolderSubdirectories)
 				if err != nil {
 					return err
 				}
 			}
 
-			err := tool.UpdateFolderSubdirectories(args[0])
+			err := currentSite.UpdateFolderSubdirectories(args[0])
 			if err != nil {
 				return err
 			}
@@ -44,14 +44,14 @@ var (
 		Args:  cobra.ExactArgs(1),
 		Short: "Updates fotoDen folder webpages",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if tool.Recurse {
-				err := tool.RecursiveVisit(args[0], tool.UpdateWeb)
+			if recurse {
+				err := currentSite.RecursiveVisit(args[0], currentSite.UpdateWeb)
 				if err != nil {
 					return err
 				}
 			}
 
-			err := tool.UpdateWeb(args[0])
+			err := currentSite.UpdateWeb(args[0])
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/vulppine/fotoDen/tool"
+)
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.PersistentFlags().StringVarP(&validateOutput, "output", "o", "text", "the format to print problems in (text or json)")
+
+	validateCmd.AddCommand(validateBuildCmd)
+	validateCmd.AddCommand(validateSiteCmd)
+	validateCmd.AddCommand(validateSchemaCmd)
+}
+
+var (
+	validateOutput string
+	validateCmd    = &cobra.Command{
+		Use:   "validate { build | site | schema }",
+		Short: "Checks fotoDen files for problems",
+		Long: `Checks fotoDen files for problems, such as unknown fields, values of the
+wrong type, images that do not exist, and image sizes that can not be generated.
+
+The JSON Schemas that fotoDen files are checked against can be printed with
+fotoDen validate schema, for use in editors and other tools.`,
+	}
+	validateBuildCmd = &cobra.Command{
+		Use:   "build buildfile...",
+		Short: "Checks build files, and every build file they include",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			problems := make([]tool.Problem, 0)
+			for _, f := range args {
+				p, err := currentSite.ValidateBuildYAML(f)
+				if err != nil {
+					return err
+				}
+
+				problems = append(problems, p...)
+			}
+
+			return printProblems(problems)
+		},
+	}
+	validateSiteCmd = &cobra.Command{
+		Use:   "site [folder]",
+		Short: "Checks the configuration of a site, and its folders and albums",
+		Long: `Checks the configuration of the current site, and every folder and album in
+the given folder of it (or the root of the site, if no folder is given).`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			folder := currentSite.RootLocation
+			if len(args) > 0 {
+				folder = args[0]
+			}
+
+			if folder == "" {
+				return fmt.Errorf("you need to use this in conjunction with a valid fotoDen site, or give a folder")
+			}
+
+			problems := make([]tool.Problem, 0)
+			if currentSite.Name != "" {
+				problems = append(problems, currentSite.ValidateConfig()...)
+			}
+
+			p, err := currentSite.ValidateSite(folder)
+			if err != nil {
+				return err
+			}
+
+			return printProblems(append(problems, p...))
+		},
+	}
+	validateSchemaCmd = &cobra.Command{
+		Use:   "schema [name]",
+		Short: "Prints the JSON Schema of a kind of fotoDen file, or lists every schema",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				fmt.Println(strings.Join(tool.SchemaNames(), "\n"))
+				return nil
+			}
+
+			d, err := tool.Schema(args[0])
+			if err != nil {
+				return err
+			}
+
+			_, err = os.Stdout.Write(d)
+			return err
+		},
+	}
+)
+
+// printProblems prints every problem found while validating, in the format
+// given by --output, and returns an error if there were any.
+func printProblems(problems []tool.Problem) error {
+	switch validateOutput {
+	case "text":
+		for _, p := range problems {
+			fmt.Println(p)
+		}
+	case "json":
+		e := json.NewEncoder(os.Stdout)
+		e.SetIndent("", "  ")
+		err := e.Encode(problems)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown output format: %s", validateOutput)
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("found %d problem(s)", len(problems))
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vulppine/fotoDen/tool"
+)
+
+func init() {
+	rootCmd.AddCommand(watchCmd)
+	watchCmd.Flags().BoolVarP(&watchOpts.Sort, "sort", "s", true, "sorts an album's images after adding")
+	watchCmd.Flags().BoolVar(&watchOpts.Copy, "copy", false, "toggle copying of images from source to fotoDen albums")
+	watchCmd.Flags().BoolVar(&watchOpts.Gensizes, "gensizes", true, "toggle generation of all image sizes from source to fotoDen albums")
+	watchCmd.Flags().BoolVar(&watchOpts.Meta, "meta", true, "toggle generation of metadata templates in fotoDen albums")
+	watchCmd.Flags().DurationVar(&debounce, "debounce", 2*time.Second, "how long a source has to stay the same before its changes are applied")
+}
+
+var (
+	debounce  time.Duration
+	watchOpts tool.GeneratorOptions
+	watchCmd  = &cobra.Command{
+		Use:   "watch [options] [folder...]",
+		Short: "Watches the sources of albums, and updates the albums when their sources change",
+		Long: `Watches the source directory of every album in the given folders (and
+their subfolders), adding new or changed images to the album and deleting
+images that were removed from the source. The source of an album is the
+directory it was last created or synced from.
+
+If no folders are given, every album in the current site is watched.`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				if currentSite.RootLocation == "" {
+					return fmt.Errorf("no folders were given, and there is no current site")
+				}
+
+				args = []string{currentSite.RootLocation}
+			}
+
+			w := currentSite.NewWatcher()
+			w.Debounce = debounce
+			for _, f := range args {
+				err := w.AddAlbums(f, watchOpts)
+				if err != nil {
+					return err
+				}
+			}
+
+			fmt.Println("Watching for changes, press Ctrl+C to stop.")
+			w.Watch(nil)
+			return nil
+		},
+	}
+)
@@ -0,0 +1,346 @@
+package tool
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vulppine/fotoDen/generator"
+)
+
+// Deployer is a remote location that a website can be deployed to,
+// e.g., a web host or an object storage bucket.
+//
+// Names are always slash-separated, and relative to the root of the remote
+// (e.g., album/img/small/small_a.jpg). What a Deployer is able to do is given
+// by its capabilities - calling a method that a Deployer is not capable of
+// returns ErrUnsupported.
+type Deployer interface {
+	// Capabilities returns what the Deployer is able to do.
+	Capabilities() DeployerCapabilities
+
+	// List returns every file in the remote.
+	List() ([]RemoteFile, error)
+
+	// Upload uploads a file into the remote, replacing it if it exists.
+	Upload(name string, data []byte) error
+
+	// Delete deletes files from the remote.
+	Delete(names ...string) error
+}
+
+// DeployerCapabilities are the things that a Deployer is able to do.
+type DeployerCapabilities struct {
+	List   bool   // the files in the remote can be listed
+	Delete bool   // files in the remote can be deleted
+	Hash   string // the hash given in every RemoteFile (either sha1, md5, or empty if there is none)
+	Atomic bool   // uploaded pages and information (HTML and JSON files) replace existing files all at once, so that they are never seen half-uploaded
+}
+
+// RemoteFile is a file in a Deployer.
+type RemoteFile struct {
+	Name string `json:"name"` // the name of the file, relative to the root of the remote
+	Size int64  `json:"size"` // the size of the file, in bytes
+	Hash string `json:"hash"` // the hash of the file, in hex (see DeployerCapabilities.Hash)
+}
+
+// ErrUnsupported is returned by a Deployer when it is asked
+// to do something that it is not capable of.
+var ErrUnsupported = errors.New("this remote does not support this")
+
+// deployIgnored are files in a website that are never deployed,
+// as they are only used in order to generate the website.
+var deployIgnored = map[string]bool{
+	"itemsCache.json": true,
+}
+
+// deployIgnoredExts are the extensions of files in a website that are never
+// deployed - build files are kept out, as they hold the paths of source images.
+var deployIgnoredExts = map[string]bool{
+	".yaml": true,
+	".yml":  true,
+}
+
+// deployFiles returns every file in a website that would be deployed,
+// by their name in the remote.
+func (s *Site) deployFiles(root string) (map[string]string, error) {
+	files := make(map[string]string)
+
+	err := generator.Walk(s.fsys(), root, func(name string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() || deployIgnored[info.Name()] || deployIgnoredExts[filepath.Ext(info.Name())] {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+
+		files[filepath.ToSlash(rel)] = name
+		return nil
+	})
+	if checkError(err) {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// Manifest is a record of every file that was pushed from a site into a remote,
+// so that later pushes only have to upload the files that changed since.
+type Manifest struct {
+	Files map[string]ManifestFile `json:"files"` // by their name in the remote
+}
+
+// ManifestFile is a file recorded in a Manifest.
+type ManifestFile struct {
+	Size int64  `json:"size"`
+	Hash string `json:"hash"` // the SHA-1 hash of the file, in hex
+}
+
+// Changeset is what a push changes in a remote.
+type Changeset struct {
+	Upload    []string `json:"upload"`    // files that are new or changed
+	Delete    []string `json:"delete"`    // files that were pushed into the remote, but are no longer in the site
+	Unchanged int      `json:"unchanged"` // the amount of files that are left alone
+}
+
+// ManifestPath returns where the manifest of a remote of the site is kept,
+// in the fotoDen config directory. If the site has no name, it has no manifests.
+func (s *Site) ManifestPath(remote string) string {
+	if s.Name == "" {
+		return ""
+	}
+
+	remote = strings.NewReplacer("/", "_", string(filepath.Separator), "_").Replace(remote)
+	return filepath.Join(generator.RootConfigDir, "sites", s.Name, "remotes", remote+".json")
+}
+
+// readManifest reads the manifest of a remote of the site. If there is no manifest
+// yet, but the remote can be listed, the manifest is made from the files in the
+// remote that are the same as in the site, so that they are left alone - files
+// in the remote that fotoDen did not push are never recorded, so that they are
+// never deleted. Otherwise, the manifest is empty.
+func (s *Site) readManifest(d Deployer, remote string, files map[string]string, data map[string][]byte) (*Manifest, error) {
+	m := &Manifest{Files: make(map[string]ManifestFile)}
+
+	p := s.ManifestPath(remote)
+	if p != "" && fileCheck(p) {
+		err := generator.ReadJSON(generator.OSFS{}, p, m)
+		if checkError(err) {
+			return nil, err
+		}
+
+		if m.Files == nil {
+			m.Files = make(map[string]ManifestFile)
+		}
+
+		return m, nil
+	}
+
+	c := d.Capabilities()
+	if !c.List || c.Hash == "" {
+		return m, nil
+	}
+
+	verbose("No manifest for " + remote + ", listing the remote")
+	remoteFiles, err := d.List()
+	if checkError(err) {
+		return nil, err
+	}
+
+	for _, r := range remoteFiles {
+		if _, ok := files[r.Name]; !ok {
+			continue
+		}
+
+		b, err := s.deployData(files, data, r.Name)
+		if checkError(err) {
+			return nil, err
+		}
+
+		if int64(len(b)) == r.Size && hashData(c.Hash, b) == strings.ToLower(r.Hash) {
+			m.Files[r.Name] = ManifestFile{Size: r.Size, Hash: hashData("sha1", b)}
+		}
+	}
+
+	return m, nil
+}
+
+// writeManifest writes the manifest of a remote of the site,
+// if the site has a name.
+func (s *Site) writeManifest(remote string, m *Manifest) error {
+	p := s.ManifestPath(remote)
+	if p == "" {
+		return nil
+	}
+
+	err := os.MkdirAll(filepath.Dir(p), 0755)
+	if checkError(err) {
+		return err
+	}
+
+	return generator.WriteJSON(generator.OSFS{}, p, "multi", m)
+}
+
+// hashData returns the hash of data in hex, using either sha1 or md5.
+func hashData(hash string, data []byte) string {
+	switch hash {
+	case "sha1":
+		h := sha1.Sum(data)
+		return hex.EncodeToString(h[:])
+	case "md5":
+		h := md5.Sum(data)
+		return hex.EncodeToString(h[:])
+	default:
+		return ""
+	}
+}
+
+// Push pushes the website in root into a Deployer, using the manifest of the remote
+// (see Site.ManifestPath) to find what changed since the last push. New and changed
+// files are uploaded, and files that were pushed before but are no longer in the
+// website are deleted from the remote, if it is able to delete files. If dryRun
+// is set, the changes are only returned, and nothing is pushed.
+//
+// Files that fail to upload or delete do not stop the push - they are returned
+// together as a generator.BatchError once every other file is pushed, and are
+// pushed again the next time.
+func (s *Site) Push(d Deployer, root string, remote string, dryRun bool) (*Changeset, error) {
+	files, err := s.deployFiles(root)
+	if checkError(err) {
+		return nil, err
+	}
+
+	return s.push(d, files, nil, remote, dryRun)
+}
+
+// push pushes files into a Deployer (see Site.Push). Files are read from the FS of
+// the site, unless they are in data, which replaces what is in the file.
+func (s *Site) push(d Deployer, files map[string]string, data map[string][]byte, remote string, dryRun bool) (*Changeset, error) {
+	m, err := s.readManifest(d, remote, files, data)
+	if checkError(err) {
+		return nil, err
+	}
+
+	changes := new(Changeset)
+	hashes := make(map[string]ManifestFile)
+	for _, name := range sortedKeys(files) {
+		b, err := s.deployData(files, data, name)
+		if checkError(err) {
+			return nil, err
+		}
+
+		f := ManifestFile{Size: int64(len(b)), Hash: hashData("sha1", b)}
+		hashes[name] = f
+
+		if old, ok := m.Files[name]; ok && old == f {
+			changes.Unchanged++
+		} else {
+			changes.Upload = append(changes.Upload, name)
+		}
+	}
+
+	for name := range m.Files {
+		if _, ok := files[name]; !ok {
+			changes.Delete = append(changes.Delete, name)
+		}
+	}
+	sort.Strings(changes.Delete)
+
+	if !d.Capabilities().Delete && len(changes.Delete) > 0 {
+		log.Printf("This remote can not delete files, leaving %d file(s) in it", len(changes.Delete))
+		changes.Delete = nil
+	}
+
+	if dryRun {
+		return changes, nil
+	}
+
+	var batchErr generator.BatchError
+	for _, name := range changes.Upload {
+		log.Println("Uploading " + name)
+		err = s.upload(d, name, files, data)
+		if checkError(err) {
+			batchErr = batchErr.Append(&generator.FileError{File: name, Op: "upload", Err: err})
+			delete(m.Files, name)
+			continue
+		}
+
+		m.Files[name] = hashes[name]
+	}
+
+	for _, name := range changes.Delete {
+		log.Println("Deleting " + name)
+		err = d.Delete(name)
+		if checkError(err) {
+			batchErr = batchErr.Append(&generator.FileError{File: name, Op: "delete", Err: err})
+			continue
+		}
+
+		delete(m.Files, name)
+	}
+
+	err = s.writeManifest(remote, m)
+	if checkError(err) {
+		return changes, err
+	}
+
+	return changes, batchErr.ErrorOrNil()
+}
+
+// PrintChangeset prints the changes of a push, in a readable form.
+func PrintChangeset(w io.Writer, c *Changeset) {
+	for _, f := range c.Upload {
+		fmt.Fprintln(w, "upload "+f)
+	}
+
+	for _, f := range c.Delete {
+		fmt.Fprintln(w, "delete "+f)
+	}
+
+	fmt.Fprintf(w, "%d to upload, %d to delete, %d unchanged\n", len(c.Upload), len(c.Delete), c.Unchanged)
+}
+
+// upload uploads a single file that is being pushed into a Deployer.
+func (s *Site) upload(d Deployer, name string, files map[string]string, data map[string][]byte) error {
+	b, err := s.deployData(files, data, name)
+	if err != nil {
+		return err
+	}
+
+	return d.Upload(name, b)
+}
+
+// deployData returns the contents of a file that is being pushed, from either
+// data if it is there, or the file in the FS of the site otherwise.
+func (s *Site) deployData(files map[string]string, data map[string][]byte, name string) ([]byte, error) {
+	if b, ok := data[name]; ok {
+		return b, nil
+	}
+
+	return s.fsys().ReadFile(files[name])
+}
+
+// sortedKeys returns the keys of a map of strings, sorted.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,355 @@
+package tool
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"sort"
+
+	"github.com/vulppine/fotoDen/generator"
+)
+
+// The categories of issues that Site.Doctor reports.
+const (
+	IssueUnreadable        = "unreadable"         // folderInfo.json or itemsInfo.json could not be read
+	IssueMissingItems      = "missing-items"      // an album has no itemsInfo.json
+	IssueItemAmount        = "item-amount"        // an album's itemAmount does not match its items
+	IssueMissingSize       = "missing-size"       // an item is missing one of its sizes
+	IssueMissingMeta       = "missing-meta"       // an item of an album with metadata is missing its metadata
+	IssueOrphan            = "orphan"             // a generated file that does not belong to any item
+	IssueMissingSubfolder  = "missing-subfolder"  // a folder lists a subfolder that is not a fotoDen folder
+	IssueUnlistedSubfolder = "unlisted-subfolder" // a fotoDen folder that is not listed by the folder it is in
+	IssueMissingThumbnail  = "missing-thumbnail"  // a folder has a thumbnail, but thumb.jpg does not exist
+)
+
+// Issue is an inconsistency in a folder or album of a site, found by Site.Doctor.
+type Issue struct {
+	Category string `json:"category"`
+	Folder   string `json:"folder"`
+	Message  string `json:"message"`
+	Fixed    bool   `json:"fixed"`
+}
+
+// Doctor checks every folder and album in a folder of the site (see Site.RecursiveVisit)
+// for inconsistencies between their information and their files, and returns
+// every inconsistency found as an Issue.
+//
+// If fix is set, issues are fixed where possible: missing sizes and metadata are
+// generated again from the album's source (or its copied source images), counts
+// and subfolders are rewritten, missing thumbnails are unset, and orphaned files
+// are removed. Items that have no source left can not be fixed.
+func (s *Site) Doctor(folder string, fix bool) ([]Issue, error) {
+	issues := make([]Issue, 0)
+
+	err := s.RecursiveVisit(folder, func(f string) error {
+		i, err := s.doctorFolder(f, fix)
+		if checkError(err) {
+			return err
+		}
+
+		issues = append(issues, i...)
+		return nil
+	})
+	if checkError(err) {
+		return nil, err
+	}
+
+	return issues, nil
+}
+
+// doctorFolder checks, and if fix is set fixes, a single folder or album.
+func (s *Site) doctorFolder(fpath string, fix bool) ([]Issue, error) {
+	issues := make([]Issue, 0)
+	add := func(category string, fixed bool, format string, a ...interface{}) {
+		issues = append(issues, Issue{category, fpath, fmt.Sprintf(format, a...), fixed})
+	}
+
+	infoPath := filepath.Join(fpath, "folderInfo.json")
+	f := new(generator.Folder)
+	err := f.ReadFolderInfo(s.fsys(), infoPath)
+	if err != nil {
+		add(IssueUnreadable, false, "folderInfo.json: %s", err)
+		return issues, nil
+	}
+
+	old := *f
+	old.Subfolders = append([]string(nil), f.Subfolders...)
+
+	listed := make(map[string]bool)
+	subfolders := make([]string, 0, len(f.Subfolders))
+	for _, sf := range f.Subfolders {
+		listed[sf] = true
+		if s.exists(filepath.Join(fpath, sf, "folderInfo.json")) {
+			subfolders = append(subfolders, sf)
+		} else {
+			add(IssueMissingSubfolder, fix, "subfolder %s is not a fotoDen folder", sf)
+		}
+	}
+
+	dir, err := s.fsys().ReadDir(fpath)
+	if checkError(err) {
+		return nil, err
+	}
+
+	for _, sf := range generator.GetArrayOfFolders(dir) {
+		if !listed[sf] && sf != s.GeneratorConfig.ImageRootDirectory && s.exists(filepath.Join(fpath, sf, "folderInfo.json")) {
+			add(IssueUnlistedSubfolder, fix, "subfolder %s is not listed", sf)
+			subfolders = append(subfolders, sf)
+		}
+	}
+
+	if f.Thumbnail && !s.exists(filepath.Join(fpath, "thumb.jpg")) {
+		add(IssueMissingThumbnail, fix, "thumbnail is set, but thumb.jpg does not exist")
+		f.Thumbnail = false
+	}
+
+	if f.Type == "album" {
+		i, n, err := s.doctorAlbum(fpath, fix)
+		if checkError(err) {
+			return nil, err
+		}
+
+		issues = append(issues, i...)
+		if n >= 0 && n != f.ItemAmount {
+			add(IssueItemAmount, fix, "itemAmount is %d, but the album has %d items", f.ItemAmount, n)
+			f.ItemAmount = n
+		}
+	}
+
+	if len(f.Subfolders) > 0 || len(subfolders) > 0 {
+		f.Subfolders = subfolders
+	}
+
+	if !fix || reflect.DeepEqual(old, *f) {
+		return issues, nil
+	}
+
+	err = f.WriteFolderInfo(s.fsys(), infoPath)
+	if checkError(err) {
+		return nil, err
+	}
+
+	err = s.UpdateWeb(fpath)
+	if checkError(err) {
+		return nil, err
+	}
+
+	return issues, nil
+}
+
+// doctorAlbum checks, and if fix is set fixes, the items of an album. Returns the issues
+// found, and the amount of items in the album (or -1, if its items could not be read).
+func (s *Site) doctorAlbum(fpath string, fix bool) ([]Issue, int, error) {
+	issues := make([]Issue, 0)
+	add := func(category string, fixed bool, format string, a ...interface{}) {
+		issues = append(issues, Issue{category, fpath, fmt.Sprintf(format, a...), fixed})
+	}
+
+	if !s.exists(filepath.Join(fpath, "itemsInfo.json")) {
+		add(IssueMissingItems, false, "album has no itemsInfo.json")
+		return issues, -1, nil
+	}
+
+	items := new(generator.Items)
+	err := items.ReadItemsInfo(s.fsys(), filepath.Join(fpath, "itemsInfo.json"))
+	if err != nil {
+		add(IssueUnreadable, false, "itemsInfo.json: %s", err)
+		return issues, -1, nil
+	}
+
+	imageRoot := filepath.Join(fpath, s.GeneratorConfig.ImageRootDirectory)
+	srcDir := filepath.Join(imageRoot, s.GeneratorConfig.ImageSrcDirectory)
+	metaDir := filepath.Join(imageRoot, s.GeneratorConfig.ImageMetaDirectory)
+
+	sizes := make([]string, 0, len(s.GeneratorConfig.ImageSizes))
+	for k := range s.GeneratorConfig.ImageSizes {
+		sizes = append(sizes, k)
+	}
+	sort.Strings(sizes)
+
+	// every file that an item should have, by directory
+	wanted := map[string]map[string]bool{
+		srcDir:  make(map[string]bool),
+		metaDir: make(map[string]bool),
+	}
+
+	for _, k := range sizes {
+		wanted[filepath.Join(imageRoot, k)] = make(map[string]bool)
+	}
+
+	missingSizes := make(map[string][]string)
+	missingMeta := make([]string, 0)
+
+	for _, i := range items.ItemsInFolder {
+		wanted[srcDir][i] = true
+		wanted[metaDir][i+".json"] = true
+
+		for _, k := range sizes {
+			d := filepath.Join(imageRoot, k)
+			names := []string{generator.SizedImageName(k, i, "")}
+			for _, format := range s.GeneratorConfig.ImageSizes[k].Formats {
+				names = append(names, generator.SizedImageName(k, i, format))
+			}
+
+			missing := false
+			for _, n := range names {
+				wanted[d][n] = true
+				missing = missing || !s.exists(filepath.Join(d, n))
+			}
+
+			if missing {
+				missingSizes[i] = append(missingSizes[i], k)
+			}
+		}
+
+		if items.Metadata && !s.exists(filepath.Join(metaDir, i+".json")) {
+			missingMeta = append(missingMeta, i)
+		}
+	}
+
+	failed := make(map[string]bool)
+	if fix && (len(missingSizes) > 0 || len(missingMeta) > 0) {
+		failed, err = s.regenerate(fpath, missingSizes, missingMeta)
+		if checkError(err) {
+			return nil, 0, err
+		}
+	}
+
+	for _, i := range items.ItemsInFolder {
+		for _, k := range missingSizes[i] {
+			add(IssueMissingSize, fix && !failed[i], "%s is missing its %s size", i, k)
+		}
+	}
+
+	for _, i := range missingMeta {
+		add(IssueMissingMeta, fix && !failed[i], "%s is missing its metadata", i)
+	}
+
+	dirs := make([]string, 0, len(wanted))
+	for d := range wanted {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+
+	for _, d := range dirs {
+		if !s.exists(d) {
+			continue
+		}
+
+		files, err := s.fsys().ReadDir(d)
+		if checkError(err) {
+			return nil, 0, err
+		}
+
+		for _, n := range generator.GetArrayOfFiles(files) {
+			if wanted[d][n] {
+				continue
+			}
+
+			removed := false
+			if fix {
+				removed = s.fsys().Remove(filepath.Join(d, n)) == nil
+			}
+
+			rel, _ := filepath.Rel(fpath, filepath.Join(d, n))
+			add(IssueOrphan, removed, "%s does not belong to any item", rel)
+		}
+	}
+
+	return issues, len(items.ItemsInFolder), nil
+}
+
+// regenerate generates the missing sizes and metadata of the items of an album again,
+// from the album's recorded source if the item is still there, or from its copied source
+// otherwise. Returns every item that could not be generated again.
+func (s *Site) regenerate(fpath string, sizes map[string][]string, meta []string) (map[string]bool, error) {
+	failed := make(map[string]bool)
+
+	cache, err := s.openCache(fpath)
+	if checkError(err) {
+		return nil, err
+	}
+
+	srcDir := filepath.Join(fpath, s.GeneratorConfig.ImageRootDirectory, s.GeneratorConfig.ImageSrcDirectory)
+	source := func(i string) string {
+		for _, d := range []string{cache.Source, srcDir} {
+			if d != "" && s.exists(filepath.Join(d, i)) {
+				return d
+			}
+		}
+
+		failed[i] = true
+		return ""
+	}
+
+	// items are grouped by their source directory, since processImages
+	// works on the files of a single directory
+	type job struct {
+		options GeneratorOptions
+		files   map[string][]string
+	}
+
+	jobs := []job{
+		{GeneratorOptions{Gensizes: true}, make(map[string][]string)},
+		{GeneratorOptions{Meta: true}, make(map[string][]string)},
+	}
+
+	for i := range sizes {
+		if d := source(i); d != "" {
+			jobs[0].files[d] = append(jobs[0].files[d], i)
+		}
+	}
+
+	for _, i := range meta {
+		if d := source(i); d != "" {
+			jobs[1].files[d] = append(jobs[1].files[d], i)
+		}
+	}
+
+	for _, j := range jobs {
+		for d, files := range j.files {
+			sort.Strings(files)
+			err := s.processImages(fpath, d, files, j.options)
+			if checkError(err) {
+				var batchErr generator.BatchError
+				if !errors.As(err, &batchErr) {
+					return nil, err
+				}
+
+				for _, f := range batchErr.Files() {
+					failed[f] = true
+				}
+			}
+		}
+	}
+
+	return failed, nil
+}
+
+// PrintIssues prints issues grouped by their category, in a readable form.
+func PrintIssues(w io.Writer, issues []Issue) {
+	categories := make([]string, 0)
+	byCategory := make(map[string][]Issue)
+	for _, i := range issues {
+		if byCategory[i.Category] == nil {
+			categories = append(categories, i.Category)
+		}
+
+		byCategory[i.Category] = append(byCategory[i.Category], i)
+	}
+	sort.Strings(categories)
+
+	for _, c := range categories {
+		fmt.Fprintf(w, "%s (%d)\n", c, len(byCategory[c]))
+		for _, i := range byCategory[c] {
+			fixed := ""
+			if i.Fixed {
+				fixed = " [fixed]"
+			}
+
+			fmt.Fprintf(w, "  %s: %s%s\n", i.Folder, i.Message, fixed)
+		}
+	}
+}
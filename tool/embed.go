@@ -4,19 +4,15 @@ package tool
 import (
 	"bytes"
 	_ "embed"
-	"os"
+
+	"github.com/vulppine/fotoDen/generator"
 )
 
 //go:embed build/fotoDen.min.js
 var fotoDenJS []byte
 
-func writefotoDenJS(j string) error {
-	f, err := os.Create(j)
-	if checkError(err) {
-		return err
-	}
-
-	_, err = f.Write(fotoDenJS)
+func writefotoDenJS(fsys generator.FS, j string) error {
+	err := fsys.WriteFile(j, fotoDenJS, 0644)
 	if checkError(err) {
 		return err
 	}
@@ -3,7 +3,6 @@ package tool
 import (
 	"fmt"
 	"os"
-	"path"
 	"path/filepath"
 
 	"github.com/vulppine/fotoDen/generator"
@@ -12,18 +11,27 @@ import (
 // UpdateFolderSubdirectories is a function to easily update a folder's subdirectories.
 //
 // Takes the path of the fotoDen folder.
+//
+// Deprecated: use Site.UpdateFolderSubdirectories instead.
 func UpdateFolderSubdirectories(fpath string) error {
+	return globalSite().UpdateFolderSubdirectories(fpath)
+}
+
+// UpdateFolderSubdirectories is a function to easily update a folder's subdirectories.
+//
+// Takes the path of the fotoDen folder.
+func (s *Site) UpdateFolderSubdirectories(fpath string) error {
 	verbose("Updating folder subdirectories in " + fpath)
 	folder := new(generator.Folder)
 
-	err := folder.ReadFolderInfo(path.Join(fpath, "folderInfo.json"))
+	err := folder.ReadFolderInfo(s.fsys(), filepath.Join(fpath, "folderInfo.json"))
 	if checkError(err) {
 		return err
 	}
 
-	folder.UpdateSubdirectories(fpath)
+	folder.UpdateSubdirectoriesExcept(s.fsys(), fpath, s.GeneratorConfig.ImageRootDirectory)
 
-	err = folder.WriteFolderInfo(path.Join(fpath, "folderInfo.json"))
+	err = folder.WriteFolderInfo(s.fsys(), filepath.Join(fpath, "folderInfo.json"))
 	if checkError(err) {
 		return err
 	}
@@ -33,8 +41,18 @@ func UpdateFolderSubdirectories(fpath string) error {
 
 // ThumbSrc represents the source of a thumbnail for a fotoDen folder.
 // This is meant to be used with the command line tool.
+//
+// Deprecated: set FolderMeta.Thumb instead.
 var ThumbSrc string
 
+// FolderMeta represents the information given to a folder
+// when it is created.
+type FolderMeta struct {
+	Name  string
+	Desc  string
+	Thumb string // The location of the image to create the folder's thumbnail from, if any.
+}
+
 // GenerateFolder generates an entire fotoDen-compatible folder from
 // any images within the current directory,
 // including thumbnails, as well as copying over the
@@ -42,42 +60,76 @@ var ThumbSrc string
 //
 // Takes the folder's name, as well as its path.
 // Returns an error if any occur.
+//
+// Deprecated: use Site.CreateFolder or Site.CreateAlbum instead.
+func GenerateFolder(meta FolderMeta, fpath string, options GeneratorOptions) error {
+	if meta.Thumb == "" {
+		meta.Thumb = ThumbSrc
+	}
+
+	return globalSite().generateFolder(meta, fpath, options)
+}
+
+// CreateFolder creates a fotoDen folder in fpath.
+// Returns an error if any occur.
+func (s *Site) CreateFolder(meta FolderMeta, fpath string) error {
+	return s.generateFolder(meta, fpath, GeneratorOptions{Static: s.Options.Static})
+}
 
-type FolderMeta struct {
-	Name string
-	Desc string
+// CreateAlbum creates a fotoDen album in fpath,
+// generating images from options.Source according to the options given.
+// Returns an error if any occur.
+func (s *Site) CreateAlbum(meta FolderMeta, fpath string, options GeneratorOptions) error {
+	options.ImageGen = true
+	return s.generateFolder(meta, fpath, options)
 }
 
-func GenerateFolder(meta FolderMeta, fpath string, options GeneratorOptions) error {
-	err := os.Mkdir(fpath, 0755)
-	if checkError(err) {
-		panic(err) // can't continue!
+// generateFolder generates an entire fotoDen-compatible folder,
+// generating an album from options.Source if options.ImageGen is set.
+func (s *Site) generateFolder(meta FolderMeta, fpath string, options GeneratorOptions) error {
+	// an existing directory is only used if it isn't a fotoDen folder already
+	if s.exists(filepath.Join(fpath, "folderInfo.json")) {
+		return fmt.Errorf("%s is already a fotoDen folder", fpath)
+	}
+
+	err := s.fsys().Mkdir(fpath, 0755)
+	if !os.IsExist(err) && checkError(err) {
+		return err // can't continue!
 	}
 
 	var folder *generator.Folder
+	var imageErr error // images that failed to generate, reported once the folder is finished
 
-	if WizardFlag {
-		folder, err = generateFolderWizard(fpath)
+	if s.Interactive {
+		folder, err = generateFolderWizard(s.fsys(), fpath)
 		if checkError(err) {
 			return err
 		}
 	} else {
-		if ThumbSrc != "" {
-			err = generator.MakeFolderThumbnail(ThumbSrc, fpath)
-			checkError(err)
-		}
-
 		folder, err = generator.GenerateFolderInfo(fpath, meta.Name)
 		if checkError(err) {
 			return err
 		}
+
+		folder.Desc = meta.Desc
+
+		if meta.Thumb != "" {
+			err = generator.MakeFolderThumbnail(s.fsys(), meta.Thumb, fpath)
+			if !checkError(err) {
+				folder.Thumbnail = true
+			}
+		}
 	}
 
 	if options.ImageGen == true {
 		verbose("Generating album...")
-		fileAmount, err := GenerateItems(fpath, options)
+		fileAmount, err := s.GenerateItems(fpath, options)
 		if checkError(err) {
-			return err
+			if !isBatchError(err) {
+				return err
+			}
+
+			imageErr = err
 		}
 
 		if fileAmount > 0 {
@@ -91,53 +143,59 @@ func GenerateFolder(meta FolderMeta, fpath string, options GeneratorOptions) err
 		folder.Type = "folder"
 	}
 
-	err = folder.WriteFolderInfo(path.Join(fpath, "folderInfo.json"))
+	err = folder.WriteFolderInfo(s.fsys(), filepath.Join(fpath, "folderInfo.json"))
 	if checkError(err) {
 		return err
 	}
 
-	// err = GenerateWeb(folder.Type, fpath, folder, options)
-	if currentTheme == nil {
-		err = openDefaultTheme()
-		if checkError(err) {
-			return err
-		}
+	t, err := s.loadTheme()
+	if checkError(err) {
+		return err
 	}
 
-	err = currentTheme.generateWeb(folder.Type, fpath, nil)
+	err = t.generateWeb(s.fsys(), s.GeneratorConfig.WebBaseURL, folder.Type, fpath, nil)
 	checkError(err)
 
-	fpath, _ = filepath.Abs(fpath)
+	fpath = filepath.Clean(fpath)
 
-	if fileCheck(path.Join(path.Dir(fpath), "folderInfo.json")) {
-		err = UpdateFolderSubdirectories(path.Dir(fpath))
+	if s.exists(filepath.Join(filepath.Dir(fpath), "folderInfo.json")) {
+		err = s.UpdateFolderSubdirectories(filepath.Dir(fpath))
 		checkError(err)
 	}
 
-	return nil
+	return imageErr
 }
 
+// UpdateFolder updates the name and description of a fotoDen folder.
+//
+// Deprecated: use Site.UpdateFolder instead.
 func UpdateFolder(folder string, name string, desc string) error {
+	return globalSite().UpdateFolder(folder, name, desc)
+}
+
+// UpdateFolder updates the name and description of a fotoDen folder.
+// If the site is interactive, the name and description are asked for instead.
+func (s *Site) UpdateFolder(folder string, name string, desc string) error {
 	fol := new(generator.Folder)
 
 	fpath := filepath.Join(folder, "folderInfo.json")
-	if !fileCheck(fpath) {
+	if !s.exists(fpath) {
 		return fmt.Errorf("folder is not a fotoDen folder, ignoring")
 	}
 
-	err := fol.ReadFolderInfo(fpath)
+	err := fol.ReadFolderInfo(s.fsys(), fpath)
 	if checkError(err) {
 		return err
 	}
 
-	if WizardFlag {
+	if s.Interactive {
 		fol = updateFolderWizard(fol)
 	} else {
 		fol.Name = name
 		fol.Desc = desc
 	}
 
-	err = fol.WriteFolderInfo(fpath)
+	err = fol.WriteFolderInfo(s.fsys(), fpath)
 	if checkError(err) {
 		return err
 	}
@@ -145,27 +203,35 @@ func UpdateFolder(folder string, name string, desc string) error {
 	return nil
 }
 
+// UpdateFolderThumbnail updates the thumbnail of a fotoDen folder.
+//
+// Deprecated: use Site.UpdateFolderThumbnail instead.
 func UpdateFolderThumbnail(folder string, file string) error {
+	return globalSite().UpdateFolderThumbnail(folder, file)
+}
+
+// UpdateFolderThumbnail creates a new thumbnail for a fotoDen folder from an image file.
+func (s *Site) UpdateFolderThumbnail(folder string, file string) error {
 	fol := new(generator.Folder)
 
 	fpath := filepath.Join(folder, "folderInfo.json")
-	if !fileCheck(fpath) {
+	if !s.exists(fpath) {
 		return fmt.Errorf("folder is not a fotoDen folder, ignoring")
 	}
 
-	err := fol.ReadFolderInfo(fpath)
+	err := fol.ReadFolderInfo(s.fsys(), fpath)
 	if checkError(err) {
 		return err
 	}
 
-	err = generator.MakeFolderThumbnail(file, folder)
+	err = generator.MakeFolderThumbnail(s.fsys(), file, folder)
 	if checkError(err) {
 		return err
 	}
 
 	fol.Thumbnail = true
 
-	err = fol.WriteFolderInfo(fpath)
+	err = fol.WriteFolderInfo(s.fsys(), fpath)
 	if checkError(err) {
 		return err
 	}
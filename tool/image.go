@@ -2,19 +2,23 @@ package tool
 
 import (
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
-	"path"
 	"path/filepath"
 	"sort"
 	"sync"
 
-	"github.com/h2non/bimg"
 	"github.com/vulppine/cmdio-go"
 	"github.com/vulppine/fotoDen/generator"
 )
 
+// GenerateItems generates the album part of a fotoDen folder in fpath.
+//
+// Deprecated: use Site.GenerateItems instead.
+func GenerateItems(fpath string, options GeneratorOptions) (int, error) {
+	return globalSite().GenerateItems(fpath, options)
+}
+
 // GenerateItems generates the album part of a fotoDen folder in fpath.
 //
 // It takes an options struct (which is just a set of options condensed into one struct)
@@ -24,91 +28,225 @@ import (
 // This is more likely a cmdio-go problem than a fotoDen problem.
 // The only solution to this right now is to not show a progress bar in verbose mode,
 // which is not preferrable.
-func GenerateItems(fpath string, options GeneratorOptions) (int, error) {
+func (s *Site) GenerateItems(fpath string, options GeneratorOptions) (int, error) {
 	verbose("GenerateItems: Current generator options: " + fmt.Sprint(options))
 	verbose("Generating item information to " + fpath)
-	var waitgroup sync.WaitGroup
 
-	items, err := generator.GenerateItemInfo(options.Source)
+	items, err := generator.GenerateItemInfo(s.fsys(), options.Source)
 	verbose("Current images in folder: " + fmt.Sprint(items.ItemsInFolder))
 
 	if len(items.ItemsInFolder) > 0 {
 		if options.Sort == true {
 			sort.Strings(items.ItemsInFolder)
 		}
-		err = MakeAlbumDirectoryStructure(fpath)
+		err = s.MakeAlbumDirectoryStructure(fpath)
 		if checkError(err) {
-			panic(err)
+			return 0, err
 		}
 
-		if options.Source != "" {
-			verbose("Changing to directory: " + options.Source)
-			wd, err := os.Getwd()
-			checkError(err)
-			fpath, err = filepath.Abs(fpath)
-			checkError(err)
-			source, err := filepath.Abs(options.Source)
-			checkError(err)
-
-			defer os.Chdir(wd)
-			os.Chdir(source)
-			verbose("Current directory: " + func() string {
-				dir, _ := os.Getwd()
-				return dir
-			}())
+		if options.Meta == true {
+			items.Metadata = true
 		}
 
-		c := make([]chan int, 0)
+		err = items.WriteItemsInfo(s.fsys(), filepath.Join(fpath, "itemsInfo.json"))
+		if checkError(err) {
+			return 0, err
+		}
 
-		if options.Copy == true {
-			ch := make(chan int, 5)
-			c = append(c, ch)
+		err = s.processImages(fpath, options.Source, items.ItemsInFolder, options)
+		if err == nil || isBatchError(err) {
+			sourceErr := s.recordSource(fpath, options.Source)
+			if checkError(sourceErr) {
+				return 0, sourceErr
+			}
+		}
+	}
 
-			ch <- len(items.ItemsInFolder)
-			waitgroup.Add(1)
-			go func(wg *sync.WaitGroup) {
-				defer wg.Done()
-				log.Println("Copying files...")
-				err = generator.BatchCopyFile(items.ItemsInFolder, path.Join(fpath, generator.CurrentConfig.ImageRootDirectory, generator.CurrentConfig.ImageSrcDirectory), ch)
-				close(ch)
-			}(&waitgroup)
+	// if only some of the images failed, the album is still usable,
+	// so the amount of images is returned alongside the error
+	if checkError(err) {
+		if isBatchError(err) {
+			return len(items.ItemsInFolder), err
+		}
+
+		return 0, err
+	}
+
+	return len(items.ItemsInFolder), nil
+}
+
+// imagePlan represents the work needed in order to bring
+// the images of an album up to date with their sources.
+type imagePlan struct {
+	hashes map[string]string   // the hash of every source image
+	copy   []string            // images that need to be copied
+	sizes  map[string][]string // images that need to be generated, by size
+	meta   []string            // images that need their metadata generated
+}
+
+// planImages compares a set of source images (relative to the source directory)
+// against an album's cache, and returns what needs to be done to the images
+// according to the given options. An image is processed again if its source
+// changed, if a size's ImageScale changed, or if the file it would generate is missing.
+func (s *Site) planImages(fpath string, source string, files []string, cache *generator.Cache, options GeneratorOptions) (*imagePlan, error) {
+	p := &imagePlan{
+		hashes: make(map[string]string),
+		sizes:  make(map[string][]string),
+	}
+
+	imageRoot := filepath.Join(fpath, s.GeneratorConfig.ImageRootDirectory)
+
+	for _, f := range files {
+		h, err := generator.HashFile(s.fsys(), filepath.Join(source, f))
+		if checkError(err) {
+			return nil, err
+		}
+
+		p.hashes[f] = h
+		changed := cache.SourceChanged(f, h)
+
+		if options.Copy && (changed || !s.exists(filepath.Join(imageRoot, s.GeneratorConfig.ImageSrcDirectory, f))) {
+			p.copy = append(p.copy, f)
 		}
 
-		if options.Gensizes == true {
-			verbose("Attempting to generate from sizes: " + fmt.Sprint(generator.CurrentConfig.ImageSizes))
-
-			for k, v := range generator.CurrentConfig.ImageSizes {
-				ch := make(chan int, 5)
-				c = append(c, ch)
-
-				ch <- len(items.ItemsInFolder)
-				sizeName := k
-				sizeOpts := v
-				waitgroup.Add(1)
-				go func(wg *sync.WaitGroup) {
-					defer wg.Done()
-					log.Printf("Generating size %s...\n", sizeName)
-					err = generator.BatchImageConversion(items.ItemsInFolder, sizeName, path.Join(fpath, generator.CurrentConfig.ImageRootDirectory, sizeName), sizeOpts, ch)
-					close(ch)
-				}(&waitgroup)
+		if options.Gensizes {
+			for k, v := range s.GeneratorConfig.ImageSizes {
+				if cache.SizeChanged(f, h, k, v) || !s.exists(filepath.Join(imageRoot, k, generator.SizedImageName(k, f, ""))) {
+					p.sizes[k] = append(p.sizes[k], f)
+				}
 			}
 		}
 
-		if options.Meta == true {
+		if options.Meta && (changed || !s.exists(filepath.Join(imageRoot, s.GeneratorConfig.ImageMetaDirectory, f+".json"))) {
+			p.meta = append(p.meta, f)
+		}
+	}
+
+	return p, nil
+}
+
+// openCache opens the cache of the album in fpath,
+// or creates a new cache if the album does not have one.
+func (s *Site) openCache(fpath string) (*generator.Cache, error) {
+	cache := generator.NewCache()
+
+	if s.exists(filepath.Join(fpath, "itemsCache.json")) {
+		err := cache.ReadCache(s.fsys(), filepath.Join(fpath, "itemsCache.json"))
+		if checkError(err) {
+			return nil, err
+		}
+	}
+
+	return cache, nil
+}
+
+// recordSource records the source directory of the album in fpath into its cache,
+// so that the album can be synced with its source later on (e.g., by a Watcher).
+// Relative sources in the OS filesystem are recorded as absolute paths.
+func (s *Site) recordSource(fpath string, source string) error {
+	cache, err := s.openCache(fpath)
+	if checkError(err) {
+		return err
+	}
+
+	cache.Source = s.sourcePath(source)
+	return cache.WriteCache(s.fsys(), filepath.Join(fpath, "itemsCache.json"))
+}
+
+// sourcePath returns the path a source directory is recorded as.
+func (s *Site) sourcePath(source string) string {
+	if s.FS == nil {
+		if p, err := filepath.Abs(source); err == nil {
+			return p
+		}
+	}
+
+	return filepath.Clean(source)
+}
+
+// processImages copies, resizes, and generates metadata for a set of source images
+// (relative to the source directory) into the album in fpath, according to the options given.
+// Only images that were added, changed, or rescaled since the album's cache was last written
+// are processed. The album's cache is updated afterwards.
+//
+// Every file that fails to process is recorded in a generator.BatchError, which is returned
+// once every other file is processed. Failed files are left out of the cache, so that they
+// are processed again the next time the album is generated.
+func (s *Site) processImages(fpath string, source string, files []string, options GeneratorOptions) error {
+	var waitgroup sync.WaitGroup
+	var errMu sync.Mutex
+	var batchErr generator.BatchError
+
+	addErr := func(err error) {
+		errMu.Lock()
+		defer errMu.Unlock()
+		batchErr = batchErr.Append(err)
+	}
+
+	cache, err := s.openCache(fpath)
+	if checkError(err) {
+		return err
+	}
+
+	plan, err := s.planImages(fpath, source, files, cache, options)
+	if checkError(err) {
+		return err
+	}
+
+	c := make([]chan int, 0)
+
+	if len(plan.copy) > 0 {
+		ch := make(chan int, 5)
+		c = append(c, ch)
+
+		ch <- len(plan.copy)
+		waitgroup.Add(1)
+		go func(wg *sync.WaitGroup) {
+			defer wg.Done()
+			log.Println("Copying files...")
+			addErr(generator.BatchCopyFile(s.fsys(), plan.copy, source, filepath.Join(fpath, s.GeneratorConfig.ImageRootDirectory, s.GeneratorConfig.ImageSrcDirectory), ch))
+			close(ch)
+		}(&waitgroup)
+	}
+
+	if len(plan.sizes) > 0 {
+		verbose("Attempting to generate from sizes: " + fmt.Sprint(s.GeneratorConfig.ImageSizes))
+
+		for k, v := range plan.sizes {
 			ch := make(chan int, 5)
 			c = append(c, ch)
 
-			ch <- len(items.ItemsInFolder)
+			ch <- len(v)
+			sizeName := k
+			sizeFiles := v
+			sizeOpts := s.GeneratorConfig.ImageSizes[k]
 			waitgroup.Add(1)
 			go func(wg *sync.WaitGroup) {
 				defer wg.Done()
-				verbose("Generating metadata to: " + path.Join(fpath, generator.CurrentConfig.ImageRootDirectory, generator.CurrentConfig.ImageMetaDirectory))
-				err = generator.BatchImageMeta(items.ItemsInFolder, path.Join(fpath, generator.CurrentConfig.ImageRootDirectory, generator.CurrentConfig.ImageMetaDirectory), ch)
+				log.Printf("Generating size %s...\n", sizeName)
+				addErr(generator.BatchImageConversion(s.fsys(), sizeFiles, source, sizeName, filepath.Join(fpath, s.GeneratorConfig.ImageRootDirectory, sizeName), sizeOpts, ch))
 				close(ch)
 			}(&waitgroup)
-			items.Metadata = true
 		}
+	}
 
+	if len(plan.meta) > 0 {
+		ch := make(chan int, 5)
+		c = append(c, ch)
+
+		ch <- len(plan.meta)
+		waitgroup.Add(1)
+		go func(wg *sync.WaitGroup) {
+			defer wg.Done()
+			verbose("Generating metadata to: " + filepath.Join(fpath, s.GeneratorConfig.ImageRootDirectory, s.GeneratorConfig.ImageMetaDirectory))
+			addErr(generator.BatchImageMeta(s.fsys(), plan.meta, source, filepath.Join(fpath, s.GeneratorConfig.ImageRootDirectory, s.GeneratorConfig.ImageMetaDirectory), ch))
+			close(ch)
+		}(&waitgroup)
+	}
+
+	if len(c) == 0 {
+		verbose("All images are up to date.")
+	} else {
 		cmdio.NewProgressBar(
 			cmdio.ProgressOptions{
 				Counters:   true,
@@ -116,142 +254,332 @@ func GenerateItems(fpath string, options GeneratorOptions) (int, error) {
 			},
 			c...,
 		)
+	}
 
-		err = items.WriteItemsInfo(path.Join(fpath, "itemsInfo.json"))
-		if checkError(err) {
-			return 0, err
+	waitgroup.Wait()
+
+	failed := make(map[string]bool)
+	for _, f := range batchErr.Files() {
+		failed[f] = true
+	}
+
+	for _, f := range files {
+		if failed[f] {
+			continue
 		}
 
-		waitgroup.Wait()
+		cache.SetImage(f, plan.hashes[f])
+		if options.Gensizes {
+			for k, v := range s.GeneratorConfig.ImageSizes {
+				cache.SetSize(f, k, v)
+			}
+		}
 	}
 
+	err = cache.WriteCache(s.fsys(), filepath.Join(fpath, "itemsCache.json"))
 	if checkError(err) {
-		panic(err)
-		// if any errors occur, something wrong happened inbetween all of the batch operations
-		// which is really, REALLY bad, considering how it's a bulk copy and conversion at the same time
-		// therefore, we need to immediately panic before continuing onwards
+		return err
 	}
 
-	return len(items.ItemsInFolder), nil
+	return batchErr.ErrorOrNil()
+}
+
+// removeImageFiles removes every file generated from a source image in an album:
+// its copied source, every size (in every format) recorded in the given sizes,
+// and its metadata. Files that do not exist are ignored.
+func (s *Site) removeImageFiles(folder string, name string, sizes map[string]generator.ImageScale) error {
+	imageRoot := filepath.Join(folder, s.GeneratorConfig.ImageRootDirectory)
+
+	files := []string{
+		filepath.Join(imageRoot, s.GeneratorConfig.ImageSrcDirectory, name),
+		filepath.Join(imageRoot, s.GeneratorConfig.ImageMetaDirectory, name+".json"),
+	}
+
+	for k, v := range sizes {
+		files = append(files, filepath.Join(imageRoot, k, generator.SizedImageName(k, name, "")))
+		for _, f := range v.Formats {
+			files = append(files, filepath.Join(imageRoot, k, generator.SizedImageName(k, name, f)))
+		}
+	}
+
+	for _, f := range files {
+		verbose("Removing " + f)
+		err := s.fsys().Remove(f)
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
 }
 
 // UpdateImages updates all the images in a fotoDen folder.
+//
+// Deprecated: use Site.UpdateImages instead.
 func UpdateImages(folder string, options GeneratorOptions) error {
-	items := new(generator.Items)
+	return globalSite().UpdateImages(folder, options)
+}
 
-	err := items.ReadItemsInfo(path.Join(folder, "itemsInfo.json"))
+// UpdateImages updates all the images in a fotoDen folder.
+//
+// The items of the folder are replaced with the images in options.Source.
+// Any image that was added, changed, or rescaled since the last time the folder
+// was generated is processed again, and any files generated from images
+// that no longer exist in the source are removed.
+func (s *Site) UpdateImages(folder string, options GeneratorOptions) error {
+	if !s.exists(filepath.Join(folder, "itemsInfo.json")) {
+		return fmt.Errorf("%s is not a fotoDen album", folder)
+	}
+
+	dir, err := s.fsys().ReadDir(options.Source)
 	if checkError(err) {
 		return err
 	}
 
-	dir, err := ioutil.ReadDir(options.Source)
+	images := make([]string, 0)
+	for _, f := range generator.IsolateImages(s.fsys(), options.Source, generator.GetArrayOfFiles(dir)) {
+		images = append(images, filepath.Join(options.Source, f))
+	}
+
+	_, err = s.syncItems(folder, images, options)
+	return err
+}
+
+// syncItems replaces the items of the album in folder with a set of images
+// (given by their location), creating the album's directory structure if needed.
+// Any image that was added, changed, or rescaled since the last time the album
+// was generated is processed again, and any files generated from images that are
+// no longer in the set are removed. If options.Source is set, it is recorded as
+// the source of the album.
+//
+// Returns the amount of items in the album. Images that fail to process are
+// returned as a generator.BatchError, once the album is synced.
+func (s *Site) syncItems(folder string, images []string, options GeneratorOptions) (int, error) {
+	items := new(generator.Items)
+	if s.exists(filepath.Join(folder, "itemsInfo.json")) {
+		err := items.ReadItemsInfo(s.fsys(), filepath.Join(folder, "itemsInfo.json"))
+		if checkError(err) {
+			return 0, err
+		}
+	}
+
+	err := s.MakeAlbumDirectoryStructure(folder)
 	if checkError(err) {
-		return err
+		return 0, err
+	}
+
+	dirs := groupByDir(images)
+	dirOrder := make([]string, 0, len(dirs))
+	for d := range dirs {
+		dirOrder = append(dirOrder, d)
+	}
+	sort.Strings(dirOrder)
+
+	// failed images are reported once the album is synced
+	var imageErr generator.BatchError
+	for _, d := range dirOrder {
+		err := s.processImages(folder, d, dirs[d], options)
+		if checkError(err) {
+			if !isBatchError(err) {
+				return 0, err
+			}
+
+			imageErr = imageErr.Append(err)
+		}
+	}
+
+	names := make([]string, 0, len(images))
+	seen := make(map[string]bool)
+	for _, i := range images {
+		n := filepath.Base(i)
+		if !seen[n] {
+			seen[n] = true
+			names = append(names, n)
+		}
 	}
 
-	if options.Source != "" {
-		verbose("Changing to directory: " + options.Source)
-		wd, err := os.Getwd()
-		checkError(err)
-		folder, err = filepath.Abs(folder)
-		checkError(err)
-		source, err := filepath.Abs(options.Source)
-		checkError(err)
-
-		defer os.Chdir(wd)
-		os.Chdir(source)
-		verbose("Current directory: " + func() string {
-			dir, _ := os.Getwd()
-			return dir
-		}())
-	}
-
-	items.ItemsInFolder = generator.IsolateImages(generator.GetArrayOfFiles(dir))
 	if options.Sort {
-		sort.Strings(items.ItemsInFolder)
+		sort.Strings(names)
 	}
 
-	err = items.WriteItemsInfo(path.Join(folder, "itemsInfo.json"))
+	cache, err := s.openCache(folder)
 	if checkError(err) {
-		return err
+		return 0, err
 	}
 
-	return nil
+	for _, n := range cache.Stale(names) {
+		verbose("Source of " + n + " no longer exists, removing its files")
+		err = s.removeImageFiles(folder, n, cache.Images[n].Sizes)
+		if checkError(err) {
+			return 0, err
+		}
+
+		delete(cache.Images, n)
+	}
+
+	if options.Source != "" {
+		cache.Source = s.sourcePath(options.Source)
+	}
+
+	err = cache.WriteCache(s.fsys(), filepath.Join(folder, "itemsCache.json"))
+	if checkError(err) {
+		return 0, err
+	}
+
+	items.ItemsInFolder = names
+	if options.Meta {
+		items.Metadata = true
+	}
+
+	err = items.WriteItemsInfo(s.fsys(), filepath.Join(folder, "itemsInfo.json"))
+	if checkError(err) {
+		return 0, err
+	}
+
+	return len(names), imageErr.ErrorOrNil()
 }
 
-// DeleteImage deletes an image from the folder.
+// DeleteImage deletes images from the folder.
 //
-// DeleteImage goes through the ItemsInFolder array of folderInfo.json,
+// Deprecated: use Site.DeleteImages instead.
+func DeleteImage(folder string, files ...string) error {
+	return globalSite().DeleteImages(folder, files...)
+}
+
+// DeleteImages deletes images from the folder.
+//
+// DeleteImages goes through the ItemsInFolder array of itemsInfo.json,
 // and deletes the name of the image from the array,
-// and then updates it accordingly.
+// and then updates it (and the ItemAmount of folderInfo.json) accordingly.
+// Every file generated from a deleted image - its sizes, its copied source,
+// and its metadata - is removed as well (see Site.DeleteItems to keep them).
 //
 // If the items in the folder are sorted, it uses sort.SearchStrings to find it in O(log n) time.
 // Otherwise, it will go through it in O(n) time.
-func DeleteImage(folder string, files ...string) error {
+func (s *Site) DeleteImages(folder string, files ...string) error {
+	return s.deleteImages(folder, false, files)
+}
+
+// DeleteItems deletes images from the folder in the same way as DeleteImages,
+// but leaves every file generated from them alone.
+func (s *Site) DeleteItems(folder string, files ...string) error {
+	return s.deleteImages(folder, true, files)
+}
+
+func (s *Site) deleteImages(folder string, keepFiles bool, files []string) error {
 	items := new(generator.Items)
 
-	err := items.ReadItemsInfo(path.Join(folder, "itemsInfo.json"))
+	err := items.ReadItemsInfo(s.fsys(), filepath.Join(folder, "itemsInfo.json"))
+	if checkError(err) {
+		return err
+	}
+
+	cache, err := s.openCache(folder)
 	if checkError(err) {
 		return err
 	}
 
 	for _, file := range files {
+		i := -1
 		if sort.StringsAreSorted(items.ItemsInFolder) {
-			result := sort.SearchStrings(items.ItemsInFolder, file)
-			if result == len(items.ItemsInFolder) {
-				copy(items.ItemsInFolder[0:result-1], items.ItemsInFolder)
-			} else if result == 0 {
-				copy(items.ItemsInFolder[1:len(items.ItemsInFolder)], items.ItemsInFolder)
-			} else if result != len(items.ItemsInFolder) && items.ItemsInFolder[result] == file {
-				items.ItemsInFolder = append(items.ItemsInFolder[0:result], items.ItemsInFolder[result+1:len(items.ItemsInFolder)]...)
-			} else {
-				log.Printf("File %s not found in items.", file)
+			if r := sort.SearchStrings(items.ItemsInFolder, file); r < len(items.ItemsInFolder) && items.ItemsInFolder[r] == file {
+				i = r
 			}
 		} else {
-			items.ItemsInFolder = generator.RemoveItemFromStringArray(items.ItemsInFolder, file)
+			for r, n := range items.ItemsInFolder {
+				if n == file {
+					i = r
+					break
+				}
+			}
+		}
+
+		if i == -1 {
+			log.Printf("File %s not found in items.", file)
+			continue
+		}
+
+		items.ItemsInFolder = append(items.ItemsInFolder[:i], items.ItemsInFolder[i+1:]...)
+		if keepFiles {
+			continue
+		}
+
+		// sizes that were never recorded in the cache are
+		// assumed to be generated from the current configuration
+		sizes := s.GeneratorConfig.ImageSizes
+		if c, ok := cache.Images[file]; ok {
+			sizes = c.Sizes
+		}
+
+		err = s.removeImageFiles(folder, file, sizes)
+		if checkError(err) {
+			return err
+		}
+
+		delete(cache.Images, file)
+	}
+
+	if !keepFiles {
+		err = cache.WriteCache(s.fsys(), filepath.Join(folder, "itemsCache.json"))
+		if checkError(err) {
+			return err
 		}
 	}
 
-	err = items.WriteItemsInfo(path.Join(folder, "itemsInfo.json"))
+	err = items.WriteItemsInfo(s.fsys(), filepath.Join(folder, "itemsInfo.json"))
 	if checkError(err) {
 		return err
 	}
 
-	return nil
+	return s.updateItemAmount(folder, len(items.ItemsInFolder))
+}
+
+// updateItemAmount sets the ItemAmount of the folderInfo.json in folder, if it has one.
+func (s *Site) updateItemAmount(folder string, amount int) error {
+	infoPath := filepath.Join(folder, "folderInfo.json")
+	if !s.exists(infoPath) {
+		return nil
+	}
+
+	f := new(generator.Folder)
+	err := f.ReadFolderInfo(s.fsys(), infoPath)
+	if checkError(err) {
+		return err
+	}
+
+	if f.ItemAmount == amount {
+		return nil
+	}
+
+	f.ItemAmount = amount
+	return f.WriteFolderInfo(s.fsys(), infoPath)
 }
 
 // InsertImage inserts an image into a fotoDen folder. Otherwise, it updates an already existing image.
+//
+// Deprecated: use Site.AddImages instead.
 func InsertImage(folder string, mode string, options GeneratorOptions, files ...string) error {
+	return globalSite().AddImages(folder, mode, options, files...)
+}
+
+// AddImages inserts images into a fotoDen folder. Otherwise, it updates the images that already exist.
+// Mode is either append (new images are added to the end of the folder) or sort (the folder is sorted afterwards).
+func (s *Site) AddImages(folder string, mode string, options GeneratorOptions, files ...string) error {
 	items := new(generator.Items)
 
-	err := items.ReadItemsInfo(path.Join(folder, "itemsInfo.json"))
+	err := items.ReadItemsInfo(s.fsys(), filepath.Join(folder, "itemsInfo.json"))
 	if checkError(err) {
 		return err
 	}
 
 	sorted := sort.StringsAreSorted(items.ItemsInFolder)
 
-	var waitgroup sync.WaitGroup
-
-	folder, err = filepath.Abs(folder)
-	checkError(err)
-	wd, err := os.Getwd()
-	checkError(err)
+	// images are processed per source directory,
+	// since the batch operations work on file names in a single directory
+	dirs := make(map[string][]string)
+	dirOrder := make([]string, 0)
 
 	for _, fv := range files {
-		fi, err := filepath.Abs(fv)
-		if checkError(err) {
-			return err
-		}
-
-		if filepath.Dir(fi) != wd {
-			verbose("Changing to directory: " + filepath.Dir(fi))
-			err := os.Chdir(filepath.Dir(fi))
-			if checkError(err) {
-				return err
-			}
-		}
-
 		f := filepath.Base(fv)
 		verbose("Current file: " + f)
 
@@ -259,7 +587,7 @@ func InsertImage(folder string, mode string, options GeneratorOptions, files ...
 
 		if sorted {
 			i := sort.SearchStrings(items.ItemsInFolder, f)
-			if items.ItemsInFolder[i] == f {
+			if i < len(items.ItemsInFolder) && items.ItemsInFolder[i] == f {
 				verbose("Image found, updating...")
 				imageExists = true
 			}
@@ -284,82 +612,40 @@ func InsertImage(folder string, mode string, options GeneratorOptions, files ...
 			}
 		}
 
-		verbose("Current directory: " + func() string {
-			dir, _ := os.Getwd()
-			return dir
-		}())
-
-		if options.Copy {
-			waitgroup.Add(1)
-			go func(wg *sync.WaitGroup) {
-				defer wg.Done()
-				fmt.Println("Copying file...")
-				err = generator.CopyFile(
-					f,
-					path.Join(
-						folder,
-						generator.CurrentConfig.ImageRootDirectory,
-						generator.CurrentConfig.ImageSrcDirectory,
-						f,
-					),
-				)
-			}(&waitgroup)
+		d := filepath.Dir(fv)
+		if _, ok := dirs[d]; !ok {
+			dirOrder = append(dirOrder, d)
 		}
+		dirs[d] = append(dirs[d], f)
+	}
 
-		if options.Gensizes {
-			for k, v := range generator.CurrentConfig.ImageSizes {
-				sizeName := k
-				sizeOpts := v
-				waitgroup.Add(1)
-				go func(wg *sync.WaitGroup) {
-					defer wg.Done()
-					fmt.Printf("Generating size %s...\n", sizeName)
-					err = generator.ResizeImage(
-						f, sizeName+"_"+f, sizeOpts,
-						path.Join(
-							folder,
-							generator.CurrentConfig.ImageRootDirectory,
-							sizeName,
-						),
-						bimg.JPEG,
-					)
-				}(&waitgroup)
+	var batchErr generator.BatchError
+
+	for _, d := range dirOrder {
+		verbose("Processing images from directory: " + d)
+		err := s.processImages(folder, d, dirs[d], options)
+		if checkError(err) {
+			if !isBatchError(err) {
+				return err
 			}
-		}
 
-		if options.Meta == true {
-			waitgroup.Add(1)
-			go func(wg *sync.WaitGroup) {
-				defer wg.Done()
-				verbose("Generating metadata to: " + generator.CurrentConfig.ImageMetaDirectory)
-				m := new(generator.ImageMeta)
-				err = m.WriteImageMeta(
-					filepath.Join(
-						folder,
-						generator.CurrentConfig.ImageRootDirectory,
-						generator.CurrentConfig.ImageMetaDirectory,
-					),
-					f,
-				)
-			}(&waitgroup)
+			batchErr = batchErr.Append(err)
 		}
+	}
 
-		os.Chdir(wd)
-		if checkError(err) {
-			return err
-		}
+	if options.Meta {
+		items.Metadata = true
 	}
 
-	waitgroup.Wait()
+	err = items.WriteItemsInfo(s.fsys(), filepath.Join(folder, "itemsInfo.json"))
 	if checkError(err) {
-		panic(err)
-		// if any errors occur, something wrong happened inbetween all of the batch operations
-		// which is really, REALLY bad, considering how it's a bulk copy and conversion at the same time
-		// therefore, we need to immediately panic before continuing onwards
+		return err
 	}
 
-	err = items.WriteItemsInfo(path.Join(folder, "itemsInfo.json"))
-	checkError(err)
+	err = s.updateItemAmount(folder, len(items.ItemsInFolder))
+	if checkError(err) {
+		return err
+	}
 
-	return nil
+	return batchErr.ErrorOrNil()
 }
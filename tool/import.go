@@ -0,0 +1,71 @@
+package tool
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/vulppine/fotoDen/generator"
+)
+
+// ImportTree creates a build file that mirrors a directory tree of images.
+//
+// Every directory that holds images becomes an album (generated from
+// that directory with the given options), and every directory that only holds
+// directories becomes a folder. Directories without any images in them (or in
+// any directory under them), and hidden directories, are left out. Everything
+// is named after the directory it comes from.
+func (s *Site) ImportTree(source string, options BuildImageOptions) (*BuildFile, error) {
+	b, err := s.importDir(source, options)
+	if checkError(err) {
+		return nil, err
+	}
+
+	if b == nil {
+		return nil, fmt.Errorf("no images were found in %s", source)
+	}
+
+	return b, nil
+}
+
+// importDir creates a build file entry from a directory, or returns nil if
+// the directory has no images in it.
+func (s *Site) importDir(dir string, options BuildImageOptions) (*BuildFile, error) {
+	d, err := s.fsys().ReadDir(dir)
+	if checkError(err) {
+		return nil, err
+	}
+
+	files, folders := generator.GetArrayOfFilesAndFolders(d)
+	src := s.sourcePath(dir)
+	b := &BuildFile{Name: filepath.Base(src)}
+
+	for _, f := range folders {
+		if strings.HasPrefix(f, ".") {
+			continue
+		}
+
+		sb, err := s.importDir(filepath.Join(dir, f), options)
+		if checkError(err) {
+			return nil, err
+		}
+
+		if sb != nil {
+			b.Subfolders = append(b.Subfolders, sb)
+		}
+	}
+
+	if len(generator.IsolateImages(s.fsys(), dir, files)) > 0 {
+		verbose("Importing " + dir + " as an album")
+		b.Type = "album"
+		b.ImageDir = src
+		b.Options = options
+	} else if len(b.Subfolders) > 0 {
+		verbose("Importing " + dir + " as a folder")
+		b.Type = "folder"
+	} else {
+		return nil, nil
+	}
+
+	return b, nil
+}
@@ -3,7 +3,7 @@ package tool
 import (
 	// "crypto/md5"
 	"fmt"
-	// "io/ioutil"
+	"io/ioutil"
 	// "log"
 	"os"
 	"path"
@@ -235,6 +235,7 @@ type WebsiteConfig struct {
 	Theme           string
 	URL             string
 	GeneratorConfig generator.Config
+	SFTP            SFTPConfig
 }
 
 // InitializefotoDenRoot sets up the root directory for fotoDen, including a folderInfo.json file.
@@ -253,12 +254,31 @@ type WebsiteConfig struct {
 // - Done, technically. Disabling several pieces and replacing them
 //   with more efficient, (hopefully?) user friendly methods of
 //   managing website resources should help with readability.
+//
+// Deprecated: use Site.Initialize instead.
 func InitializefotoDenRoot(rootpath string, webconfig WebsiteConfig) error {
-	rootpath, _ = filepath.Abs(rootpath)
+	s := &Site{WebsiteConfig: webconfig, Interactive: WizardFlag}
+	err := s.Initialize(rootpath, URLFlag)
+	generator.CurrentConfig = s.GeneratorConfig
+
+	return err
+}
+
+// Initialize sets up the root directory of a new site in rootpath, as well as its
+// configuration in the fotoDen config directory. The site's name, URL and theme are
+// taken from its WebsiteConfig. Takes the URL images are stored in, if they are
+// stored remotely.
+//
+// See InitializefotoDenRoot for more information.
+func (s *Site) Initialize(rootpath string, source string) error {
+	webconfig := s.WebsiteConfig
+	if s.FS == nil {
+		rootpath, _ = filepath.Abs(rootpath) // so that the site can be found from anywhere
+	}
 
-	err := GenerateWebRoot(rootpath)
+	err := generateWebRoot(s.fsys(), rootpath)
 	if checkError(err) {
-		panic(err)
+		return err
 	}
 
 	var w *generator.WebConfig
@@ -275,6 +295,8 @@ func InitializefotoDenRoot(rootpath string, webconfig WebsiteConfig) error {
 		if checkError(err) {
 			return err
 		}
+
+		webconfig.Theme, _ = filepath.Abs(webconfig.Theme) // so that the site can find its theme from anywhere
 	} else if isEmbed {
 		verbose("no theme given, attempting to use internal default")
 		t, err = openTheme(defaultThemeZipReader(), defaultThemeZipLen)
@@ -310,9 +332,10 @@ func InitializefotoDenRoot(rootpath string, webconfig WebsiteConfig) error {
 		}
 	*/
 
-	if WizardFlag == true {
+	if s.Interactive {
+		theme := webconfig.Theme
 		webconfig, w = setupWebsite(rootpath, t.s.ThemeName)
-		generator.CurrentConfig = webconfig.GeneratorConfig
+		webconfig.Theme = theme
 	} else {
 		webconfig.RootLocation = rootpath
 		webconfig.GeneratorConfig = generator.DefaultConfig
@@ -330,8 +353,7 @@ func InitializefotoDenRoot(rootpath string, webconfig WebsiteConfig) error {
 				"theme",
 				t.s.ThemeName,
 			))
-		generator.CurrentConfig = webconfig.GeneratorConfig
-		w = generator.GenerateWebConfig(URLFlag)
+		w = webconfig.GeneratorConfig.WebConfig(source)
 		w.Theme = true // we're generating this from fotoDen tool, so we're using a theme obviously
 		w.WebsiteTitle = webconfig.Name
 		w.ImageRootDir = webconfig.GeneratorConfig.ImageRootDirectory
@@ -341,7 +363,7 @@ func InitializefotoDenRoot(rootpath string, webconfig WebsiteConfig) error {
 			w.DownloadSizes = append(w.DownloadSizes, v.SizeName)
 		}
 
-		if URLFlag == "" {
+		if source == "" {
 			fmt.Printf("You will have to configure your photo storage provider in %v.\n", path.Join(rootpath, "config.json"))
 		}
 	}
@@ -353,12 +375,13 @@ func InitializefotoDenRoot(rootpath string, webconfig WebsiteConfig) error {
 		return err
 	}
 
-	err = generator.WriteJSON(path.Join(spath, "config.json"), "multi", webconfig)
+	err = generator.WriteJSON(generator.OSFS{}, path.Join(spath, "config.json"), "multi", webconfig)
 	if checkError(err) {
 		return err
 	}
 
-	generator.CurrentConfig = webconfig.GeneratorConfig
+	s.WebsiteConfig = webconfig
+	s.theme = t
 
 	/*
 		err = InitializeWebTheme(
@@ -368,25 +391,30 @@ func InitializefotoDenRoot(rootpath string, webconfig WebsiteConfig) error {
 		)
 	*/
 
-	err = w.WriteWebConfig(path.Join(rootpath, "config.json"))
+	err = w.WriteWebConfig(s.fsys(), filepath.Join(rootpath, "config.json"))
 	if checkError(err) {
 		return err
 	}
 
 	if isEmbed {
 		verbose("copying fotoDen.js from internal embed file")
-		err = writefotoDenJS(path.Join(rootpath, "js", "fotoDen.js"))
+		err = writefotoDenJS(s.fsys(), filepath.Join(rootpath, "js", "fotoDen.js"))
 		checkError(err)
 	} else {
 		verbose("copying fotoDen.js from config dir")
-		err = generator.CopyFile(path.Join(generator.RootConfigDir, "fotoDen.js"), path.Join(rootpath, "js", "fotoDen.js"))
+		var js []byte
+		js, err = ioutil.ReadFile(path.Join(generator.RootConfigDir, "fotoDen.js"))
+		if !checkError(err) {
+			err = s.fsys().WriteFile(filepath.Join(rootpath, "js", "fotoDen.js"), js, 0644)
+		}
 		checkError(err)
 	}
 
 	t.initTheme(
+		s.fsys(),
 		webconfig.URL,
 		path.Join(spath, "theme", t.s.ThemeName), // change this
-		path.Join(rootpath, "theme"),
+		filepath.Join(rootpath, "theme"),
 	)
 
 	/*
@@ -433,11 +461,11 @@ func InitializefotoDenRoot(rootpath string, webconfig WebsiteConfig) error {
 	folder, err := generator.GenerateFolderInfo(rootpath, w.WebsiteTitle) // do it in rootpath since we're not trying to scan for images in the current folder
 	folder.Type = "folder"
 	checkError(err)
-	err = folder.WriteFolderInfo(path.Join(rootpath, "folderInfo.json"))
+	err = folder.WriteFolderInfo(s.fsys(), filepath.Join(rootpath, "folderInfo.json"))
 	checkError(err)
 
 	// err = GenerateWeb("folder", rootpath, folder, Genoptions)
-	err = t.generateWeb("folder", rootpath, nil)
+	err = t.generateWeb(s.fsys(), webconfig.GeneratorConfig.WebBaseURL, "folder", rootpath, nil)
 	checkError(err)
 
 	if !fileCheck(path.Join(generator.RootConfigDir, "defaultsite")) {
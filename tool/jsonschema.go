@@ -0,0 +1,342 @@
+package tool
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// schemaError is a value that does not match a JSON Schema. Path is the
+// location of the value (e.g., /subfolders/0/type), in order to find its line.
+type schemaError struct {
+	Path    string
+	Message string
+}
+
+// schemaValidator validates values against the published JSON Schemas (see Schema).
+//
+// Only what the published schemas use is supported: type, enum, properties,
+// additionalProperties, required, items, minimum, maximum, oneOf, and $ref
+// into the definitions of the same schema. Values are what encoding/json
+// decodes into an interface{}, with every number as a float64.
+type schemaValidator struct {
+	root   map[string]interface{}
+	errors []schemaError
+}
+
+// validateSchema validates a value against the published JSON Schema with the given name.
+func validateSchema(name string, v interface{}) ([]schemaError, error) {
+	d, err := Schema(name)
+	if err != nil {
+		return nil, err
+	}
+
+	root := make(map[string]interface{})
+	err = json.Unmarshal(d, &root)
+	if err != nil {
+		return nil, fmt.Errorf("schema %s: %w", name, err)
+	}
+
+	sv := &schemaValidator{root: root}
+	sv.validate(root, v, "")
+	return sv.errors, nil
+}
+
+// add adds an error about the value at path. The message is prefixed with
+// the location of the value it is about, unless that is the top of the file.
+func (sv *schemaValidator) add(path string, at string, format string, a ...interface{}) {
+	msg := fmt.Sprintf(format, a...)
+	if at != "" {
+		msg = strings.TrimPrefix(at, "/") + ": " + msg
+	}
+
+	sv.errors = append(sv.errors, schemaError{Path: path, Message: msg})
+}
+
+func (sv *schemaValidator) validate(schema map[string]interface{}, v interface{}, path string) {
+	if ref, ok := schema["$ref"].(string); ok {
+		schema = sv.resolve(ref)
+	}
+
+	if t, ok := schema["type"]; ok && !schemaType(t, v) {
+		sv.add(path, path, "expected %s, got %s", schemaTypeNames(t), jsonType(v))
+		return
+	}
+
+	if e, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, ev := range e {
+			found = found || reflect.DeepEqual(ev, v)
+		}
+
+		if !found {
+			allowed := make([]string, 0, len(e))
+			for _, ev := range e {
+				allowed = append(allowed, fmt.Sprint(ev))
+			}
+
+			sv.add(path, path, "%v is not one of %s", v, strings.Join(allowed, ", "))
+			return
+		}
+	}
+
+	if one, ok := schema["oneOf"].([]interface{}); ok {
+		sv.oneOf(one, v, path)
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		props, _ := schema["properties"].(map[string]interface{})
+
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			child := path + "/" + k
+			if p, ok := props[k].(map[string]interface{}); ok {
+				sv.validate(p, v[k], child)
+				continue
+			}
+
+			switch ap := schema["additionalProperties"].(type) {
+			case bool:
+				if !ap {
+					sv.add(child, path, "unknown field %s", k)
+				}
+			case map[string]interface{}:
+				sv.validate(ap, v[k], child)
+			}
+		}
+
+		if r, ok := schema["required"].([]interface{}); ok {
+			for _, k := range r {
+				if _, ok := v[fmt.Sprint(k)]; !ok {
+					sv.add(path, path, "%v is required", k)
+				}
+			}
+		}
+	case []interface{}:
+		if items, ok := schema["items"].(map[string]interface{}); ok {
+			for i, iv := range v {
+				sv.validate(items, iv, path+"/"+strconv.Itoa(i))
+			}
+		}
+	case float64:
+		if min, ok := schema["minimum"].(float64); ok && v < min {
+			sv.add(path, path, "%v is less than %v", v, min)
+		}
+
+		if max, ok := schema["maximum"].(float64); ok && v > max {
+			sv.add(path, path, "%v is more than %v", v, max)
+		}
+	}
+}
+
+// oneOf checks that a value matches exactly one of several schemas. If it matches
+// none of them, the errors of the first schema of the same type as the value are
+// given, as that is most likely the one that was meant.
+func (sv *schemaValidator) oneOf(schemas []interface{}, v interface{}, path string) {
+	var meant []schemaError
+	matched := 0
+	for _, s := range schemas {
+		schema, _ := s.(map[string]interface{})
+		sub := &schemaValidator{root: sv.root}
+		sub.validate(schema, v, path)
+
+		if len(sub.errors) == 0 {
+			matched++
+			continue
+		}
+
+		if meant == nil && (schema["type"] == nil || schemaType(schema["type"], v)) {
+			meant = sub.errors
+		}
+	}
+
+	switch {
+	case matched == 1:
+	case matched > 1:
+		sv.add(path, path, "matches more than one of the forms it can have")
+	case meant != nil:
+		sv.errors = append(sv.errors, meant...)
+	default:
+		sv.add(path, path, "%s does not match any of the forms it can have", jsonType(v))
+	}
+}
+
+// resolve returns the schema that a $ref points to. Only references
+// into the schema itself (e.g., #/definitions/entry) are supported.
+func (sv *schemaValidator) resolve(ref string) map[string]interface{} {
+	schema := sv.root
+	for _, p := range strings.Split(strings.TrimPrefix(ref, "#"), "/") {
+		if p == "" {
+			continue
+		}
+
+		schema, _ = schema[p].(map[string]interface{})
+	}
+
+	return schema
+}
+
+// schemaType checks if a value is of the type (or one of the types) in a schema.
+func schemaType(t interface{}, v interface{}) bool {
+	if l, ok := t.([]interface{}); ok {
+		for _, lt := range l {
+			if schemaType(lt, v) {
+				return true
+			}
+		}
+
+		return false
+	}
+
+	vt := jsonType(v)
+	switch t {
+	case "integer":
+		f, ok := v.(float64)
+		return ok && f == math.Trunc(f)
+	case "number":
+		return vt == "number"
+	default:
+		return vt == t
+	}
+}
+
+// schemaTypeNames returns the type (or types) in a schema, in a readable form.
+func schemaTypeNames(t interface{}) string {
+	l, ok := t.([]interface{})
+	if !ok {
+		return fmt.Sprintf("%s", t)
+	}
+
+	names := make([]string, 0, len(l))
+	for _, lt := range l {
+		names = append(names, fmt.Sprint(lt))
+	}
+
+	return strings.Join(names, " or ")
+}
+
+// jsonType returns the JSON type of a value.
+func jsonType(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", v)
+	}
+}
+
+// decodeJSONLines decodes a JSON document into a value that can be validated against a
+// JSON Schema, alongside the line of every value in it, by its path (see schemaError).
+func decodeJSONLines(d []byte) (interface{}, map[string]int, error) {
+	dec := json.NewDecoder(bytes.NewReader(d))
+	lines := make(map[string]int)
+
+	var value func(path string) (interface{}, error)
+	value = func(path string) (interface{}, error) {
+		t, err := dec.Token()
+		if err == io.EOF {
+			return nil, io.ErrUnexpectedEOF
+		} else if err != nil {
+			return nil, err
+		}
+
+		lines[path] = bytes.Count(d[:dec.InputOffset()], []byte("\n")) + 1
+
+		switch t {
+		case json.Delim('{'):
+			m := make(map[string]interface{})
+			for dec.More() {
+				k, err := dec.Token()
+				if err != nil {
+					return nil, err
+				}
+
+				key := fmt.Sprint(k)
+				m[key], err = value(path + "/" + key)
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			_, err = dec.Token()
+			return m, err
+		case json.Delim('['):
+			l := make([]interface{}, 0)
+			for i := 0; dec.More(); i++ {
+				v, err := value(path + "/" + strconv.Itoa(i))
+				if err != nil {
+					return nil, err
+				}
+
+				l = append(l, v)
+			}
+
+			_, err = dec.Token()
+			return l, err
+		}
+
+		return t, nil
+	}
+
+	v, err := value("")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if _, err = dec.Token(); err != io.EOF {
+		return nil, nil, fmt.Errorf("invalid data after the end of the file")
+	}
+
+	return v, lines, nil
+}
+
+// yamlValue turns a value decoded by the yaml package into a value that can be
+// validated against a JSON Schema, with every mapping key as a string and every number as a float64.
+func yamlValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case yamlMap:
+		m := make(map[string]interface{}, len(v))
+		for k, mv := range v {
+			m[fmt.Sprint(k)] = yamlValue(mv)
+		}
+
+		return m
+	case []interface{}:
+		l := make([]interface{}, 0, len(v))
+		for _, lv := range v {
+			l = append(l, yamlValue(lv))
+		}
+
+		return l
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case uint64:
+		return float64(v)
+	}
+
+	return v
+}
@@ -0,0 +1,323 @@
+package tool
+
+import (
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vulppine/fotoDen/generator"
+)
+
+// MoveFolder moves a fotoDen folder or album, and everything in it, to dest.
+// Dest must not exist yet, but the directory it is in must.
+//
+// The folder is removed from the subfolders of the folder it was in, and added
+// to the subfolders of the folder it is moved into. The pages of the folder are
+// generated again, so that they refer to the folder they are now in.
+func (s *Site) MoveFolder(folder string, dest string) error {
+	folder = filepath.Clean(folder)
+	dest = filepath.Clean(dest)
+
+	if !s.exists(filepath.Join(folder, "folderInfo.json")) {
+		return fmt.Errorf("%s is not a fotoDen folder", folder)
+	}
+
+	if s.exists(dest) {
+		return fmt.Errorf("%s already exists", dest)
+	}
+
+	if rel, err := filepath.Rel(folder, dest); err == nil && !strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("%s can't be moved into itself", folder)
+	}
+
+	if !s.exists(filepath.Dir(dest)) {
+		return fmt.Errorf("%s does not exist", filepath.Dir(dest))
+	}
+
+	verbose("Moving " + folder + " to " + dest)
+	err := generator.Rename(s.fsys(), folder, dest)
+	if checkError(err) {
+		return err
+	}
+
+	if filepath.Dir(folder) == filepath.Dir(dest) {
+		err = s.renameSubfolder(filepath.Dir(dest), filepath.Base(folder), filepath.Base(dest))
+	} else {
+		err = s.renameSubfolder(filepath.Dir(folder), filepath.Base(folder), "")
+		if !checkError(err) {
+			err = s.renameSubfolder(filepath.Dir(dest), "", filepath.Base(dest))
+		}
+	}
+	if checkError(err) {
+		return err
+	}
+
+	return s.UpdateWeb(dest)
+}
+
+// RenameFolder renames the directory of a fotoDen folder or album (see Site.MoveFolder).
+func (s *Site) RenameFolder(folder string, name string) error {
+	if name != filepath.Base(name) {
+		return fmt.Errorf("%s is not a valid folder name", name)
+	}
+
+	return s.MoveFolder(folder, filepath.Join(filepath.Dir(filepath.Clean(folder)), name))
+}
+
+// renameSubfolder renames a subfolder in the folderInfo.json of a folder, keeping its place.
+// If oldName is empty, newName is added to the end of the subfolders. If newName is empty,
+// oldName is removed. Folders that are not fotoDen folders are left alone.
+func (s *Site) renameSubfolder(folder string, oldName string, newName string) error {
+	infoPath := filepath.Join(folder, "folderInfo.json")
+	if !s.exists(infoPath) {
+		return nil
+	}
+
+	f := new(generator.Folder)
+	err := f.ReadFolderInfo(s.fsys(), infoPath)
+	if checkError(err) {
+		return err
+	}
+
+	subfolders := make([]string, 0, len(f.Subfolders)+1)
+	found := false
+	for _, sf := range f.Subfolders {
+		switch {
+		case sf == newName:
+			found = true
+			subfolders = append(subfolders, sf)
+		case sf == oldName:
+			if newName != "" && !found {
+				found = true
+				subfolders = append(subfolders, newName)
+			}
+		default:
+			subfolders = append(subfolders, sf)
+		}
+	}
+
+	if newName != "" && !found {
+		subfolders = append(subfolders, newName)
+	}
+
+	f.Subfolders = subfolders
+	return f.WriteFolderInfo(s.fsys(), infoPath)
+}
+
+// MergeAlbums merges the album in src into the album in dest, and removes src.
+//
+// The items of src are added to the end of the items of dest (or sorted into them,
+// if the items of dest are sorted), alongside their sizes, copied sources and metadata.
+// An item with the same name as an item in dest is renamed by adding a number to
+// its name (e.g., image-2.jpg). The merged album keeps the source of dest - syncing it
+// with that source again removes every item that came from src.
+//
+// Folders and albums inside src are moved into dest (see Site.MoveFolder), and
+// added to its subfolders. If dest already has something with the same name as
+// one of them, nothing is merged.
+func (s *Site) MergeAlbums(src string, dest string) error {
+	src = filepath.Clean(src)
+	dest = filepath.Clean(dest)
+
+	if src == dest {
+		return fmt.Errorf("an album can't be merged into itself")
+	}
+
+	for _, a := range []string{src, dest} {
+		if !s.exists(filepath.Join(a, "itemsInfo.json")) {
+			return fmt.Errorf("%s is not a fotoDen album", a)
+		}
+	}
+
+	subfolders, err := s.subfolders(src)
+	if checkError(err) {
+		return err
+	}
+
+	for _, sf := range subfolders {
+		if s.exists(filepath.Join(dest, sf)) {
+			return fmt.Errorf("%s can't be moved into %s, as %s already exists", filepath.Join(src, sf), dest, filepath.Join(dest, sf))
+		}
+	}
+
+	srcItems := new(generator.Items)
+	err = srcItems.ReadItemsInfo(s.fsys(), filepath.Join(src, "itemsInfo.json"))
+	if checkError(err) {
+		return err
+	}
+
+	destItems := new(generator.Items)
+	err = destItems.ReadItemsInfo(s.fsys(), filepath.Join(dest, "itemsInfo.json"))
+	if checkError(err) {
+		return err
+	}
+
+	srcCache, err := s.openCache(src)
+	if checkError(err) {
+		return err
+	}
+
+	destCache, err := s.openCache(dest)
+	if checkError(err) {
+		return err
+	}
+
+	err = s.MakeAlbumDirectoryStructure(dest)
+	if checkError(err) {
+		return err
+	}
+
+	taken := make(map[string]bool)
+	for _, i := range destItems.ItemsInFolder {
+		taken[i] = true
+	}
+
+	for n := range destCache.Images {
+		taken[n] = true
+	}
+
+	// copied sources that are in dest without being an item are not overwritten either
+	destSrc := filepath.Join(dest, s.GeneratorConfig.ImageRootDirectory, s.GeneratorConfig.ImageSrcDirectory)
+	sorted := sort.StringsAreSorted(destItems.ItemsInFolder)
+
+	for _, i := range srcItems.ItemsInFolder {
+		name := i
+		for c := 2; taken[name] || s.exists(filepath.Join(destSrc, name)); c++ {
+			ext := filepath.Ext(i)
+			name = strings.TrimSuffix(i, ext) + "-" + strconv.Itoa(c) + ext
+		}
+		taken[name] = true
+
+		if name != i {
+			log.Printf("%s is already in %s, renaming it to %s", i, dest, name)
+		}
+
+		sizes := s.GeneratorConfig.ImageSizes
+		if c, ok := srcCache.Images[i]; ok {
+			sizes = c.Sizes
+			destCache.Images[name] = c
+		}
+
+		err = s.moveImageFiles(src, i, dest, name, sizes)
+		if checkError(err) {
+			return err
+		}
+
+		destItems.ItemsInFolder = append(destItems.ItemsInFolder, name)
+	}
+
+	if sorted {
+		sort.Strings(destItems.ItemsInFolder)
+	}
+
+	destItems.Metadata = destItems.Metadata || srcItems.Metadata
+
+	err = destCache.WriteCache(s.fsys(), filepath.Join(dest, "itemsCache.json"))
+	if checkError(err) {
+		return err
+	}
+
+	err = destItems.WriteItemsInfo(s.fsys(), filepath.Join(dest, "itemsInfo.json"))
+	if checkError(err) {
+		return err
+	}
+
+	err = s.updateItemAmount(dest, len(destItems.ItemsInFolder))
+	if checkError(err) {
+		return err
+	}
+
+	for _, sf := range subfolders {
+		err = s.MoveFolder(filepath.Join(src, sf), filepath.Join(dest, sf))
+		if checkError(err) {
+			return err
+		}
+	}
+
+	log.Println("Removing " + src)
+	err = s.removeAll(src)
+	if checkError(err) {
+		return err
+	}
+
+	return s.renameSubfolder(filepath.Dir(src), filepath.Base(src), "")
+}
+
+// subfolders returns the names of every fotoDen folder or album inside a folder,
+// in the order of its subfolders, followed by any that are not listed in them.
+func (s *Site) subfolders(folder string) ([]string, error) {
+	f := new(generator.Folder)
+	err := f.ReadFolderInfo(s.fsys(), filepath.Join(folder, "folderInfo.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := s.fsys().ReadDir(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	found := make(map[string]bool)
+	for _, sf := range generator.GetArrayOfFolders(dir) {
+		if sf != s.GeneratorConfig.ImageRootDirectory && s.exists(filepath.Join(folder, sf, "folderInfo.json")) {
+			found[sf] = true
+		}
+	}
+
+	subfolders := make([]string, 0, len(found))
+	for _, sf := range f.Subfolders {
+		if found[sf] {
+			subfolders = append(subfolders, sf)
+			delete(found, sf)
+		}
+	}
+
+	for _, sf := range generator.GetArrayOfFolders(dir) {
+		if found[sf] {
+			subfolders = append(subfolders, sf)
+		}
+	}
+
+	return subfolders, nil
+}
+
+// moveImageFiles moves every file generated from a source image in an album
+// (see removeImageFiles) into another album, under a new name.
+// Files that do not exist are ignored.
+func (s *Site) moveImageFiles(folder string, name string, dest string, newName string, sizes map[string]generator.ImageScale) error {
+	imageRoot := filepath.Join(folder, s.GeneratorConfig.ImageRootDirectory)
+	destRoot := filepath.Join(dest, s.GeneratorConfig.ImageRootDirectory)
+
+	files := map[string]string{
+		filepath.Join(imageRoot, s.GeneratorConfig.ImageSrcDirectory, name):          filepath.Join(destRoot, s.GeneratorConfig.ImageSrcDirectory, newName),
+		filepath.Join(imageRoot, s.GeneratorConfig.ImageMetaDirectory, name+".json"): filepath.Join(destRoot, s.GeneratorConfig.ImageMetaDirectory, newName+".json"),
+	}
+
+	for k, v := range sizes {
+		for _, f := range append([]string{""}, v.Formats...) {
+			files[filepath.Join(imageRoot, k, generator.SizedImageName(k, name, f))] = filepath.Join(destRoot, k, generator.SizedImageName(k, newName, f))
+		}
+	}
+
+	for from, to := range files {
+		if !s.exists(from) {
+			continue
+		}
+
+		err := s.fsys().MkdirAll(filepath.Dir(to), 0755)
+		if checkError(err) {
+			return err
+		}
+
+		verbose("Moving " + from + " to " + to)
+		err = generator.Rename(s.fsys(), from, to)
+		if checkError(err) {
+			return err
+		}
+	}
+
+	return nil
+}
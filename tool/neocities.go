@@ -0,0 +1,220 @@
+package tool
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vulppine/fotoDen/generator"
+)
+
+// NeoCitiesDeployer is a Deployer for a NeoCities website,
+// using the NeoCities API.
+//
+// Requests that are rate limited, or that fail because of the server, are tried
+// again after waiting for Backoff, with the wait doubling every time (or for as
+// long as the server asks), up to Retries times.
+type NeoCitiesDeployer struct {
+	Key string // the API key of the website
+	API string // the URL of the API (https://neocities.org, if empty)
+
+	Retries int           // how many times a request is tried again (5, if zero - negative to never try again)
+	Backoff time.Duration // how long to wait before trying a request again at first (one second, if zero)
+
+	Client *http.Client // the client used for requests (http.DefaultClient, if nil)
+}
+
+// NeoCitiesKeyEnv is the environment variable that the API key of a NeoCities website can be given in.
+const NeoCitiesKeyEnv = "NEOCITIES_API_KEY"
+
+// NeoCitiesKey returns the API key of the NeoCities website of the site, from either
+// the NEOCITIES_API_KEY environment variable, or the neocities_key file in the
+// directory of the site in the fotoDen config directory.
+func (s *Site) NeoCitiesKey() (string, error) {
+	if k := os.Getenv(NeoCitiesKeyEnv); k != "" {
+		return k, nil
+	}
+
+	if s.Name != "" {
+		p := filepath.Join(generator.RootConfigDir, "sites", s.Name, "neocities_key")
+		if fileCheck(p) {
+			return ReadKeyFile(p)
+		}
+	}
+
+	return "", fmt.Errorf("no NeoCities API key was given, in either %s or the site's neocities_key file", NeoCitiesKeyEnv)
+}
+
+// ReadKeyFile reads an API key from a file, ignoring any whitespace around it.
+func ReadKeyFile(file string) (string, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
+
+func (d *NeoCitiesDeployer) Capabilities() DeployerCapabilities {
+	return DeployerCapabilities{
+		List:   true,
+		Delete: true,
+		Hash:   "sha1",
+	}
+}
+
+// neocitiesResponse is a response from the NeoCities API.
+type neocitiesResponse struct {
+	Result  string `json:"result"`
+	Message string `json:"message"`
+	Files   []struct {
+		Path        string `json:"path"`
+		IsDirectory bool   `json:"is_directory"`
+		Size        int64  `json:"size"`
+		SHA1        string `json:"sha1_hash"`
+	} `json:"files"`
+}
+
+func (d *NeoCitiesDeployer) List() ([]RemoteFile, error) {
+	r, err := d.do("GET", "/api/list", "", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]RemoteFile, 0, len(r.Files))
+	for _, f := range r.Files {
+		if f.IsDirectory {
+			continue
+		}
+
+		files = append(files, RemoteFile{Name: f.Path, Size: f.Size, Hash: f.SHA1})
+	}
+
+	return files, nil
+}
+
+func (d *NeoCitiesDeployer) Upload(name string, data []byte) error {
+	body := new(bytes.Buffer)
+	w := multipart.NewWriter(body)
+
+	p, err := w.CreateFormFile(name, name)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.Write(data)
+	if err != nil {
+		return err
+	}
+
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+
+	_, err = d.do("POST", "/api/upload", w.FormDataContentType(), body.Bytes())
+	return err
+}
+
+func (d *NeoCitiesDeployer) Delete(names ...string) error {
+	if len(names) == 0 {
+		return nil
+	}
+
+	form := url.Values{"filenames[]": names}
+	_, err := d.do("POST", "/api/delete", "application/x-www-form-urlencoded", []byte(form.Encode()))
+	return err
+}
+
+// do sends an authorized request to the NeoCities API, trying it again if it is
+// rate limited or fails because of the server, and returns its response.
+func (d *NeoCitiesDeployer) do(method string, endpoint string, contentType string, body []byte) (*neocitiesResponse, error) {
+	retries := d.Retries
+	if retries == 0 {
+		retries = 5
+	}
+
+	wait := d.Backoff
+	if wait == 0 {
+		wait = time.Second
+	}
+
+	for i := 0; ; i++ {
+		r, retryAfter, err := d.try(method, endpoint, contentType, body)
+		if retryAfter < 0 || i >= retries {
+			return r, err
+		}
+
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+
+		verbose(fmt.Sprintf("%s, trying again in %s", err, wait))
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
+
+// try sends a single request to the NeoCities API. If the request can be tried
+// again, it returns how long the server asked to wait before then (which may be
+// zero) - otherwise, it returns a negative duration.
+func (d *NeoCitiesDeployer) try(method string, endpoint string, contentType string, body []byte) (*neocitiesResponse, time.Duration, error) {
+	api := d.API
+	if api == "" {
+		api = "https://neocities.org"
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(api, "/")+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, -1, err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+d.Key)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	c := d.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	retryAfter := time.Duration(-1)
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+		retryAfter = 0
+		if n, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			retryAfter = time.Duration(n) * time.Second
+		}
+	}
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, retryAfter, err
+	}
+
+	r := new(neocitiesResponse)
+	if json.Unmarshal(b, r) != nil {
+		return nil, retryAfter, fmt.Errorf("neocities: %s %s: %s", method, endpoint, resp.Status)
+	}
+
+	if r.Result != "success" {
+		return nil, retryAfter, fmt.Errorf("neocities: %s %s: %s", method, endpoint, r.Message)
+	}
+
+	return r, -1, nil
+}
@@ -0,0 +1,245 @@
+package tool
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/vulppine/fotoDen/generator"
+)
+
+// BuildPlan represents what building a build file would do to
+// a single folder or album, without anything being built.
+type BuildPlan struct {
+	Path   string `json:"path"`   // The location of the folder/album.
+	Type   string `json:"type"`   // Either folder or album.
+	Action string `json:"action"` // Either create, update, delete, or none.
+
+	Add    []string            `json:"add,omitempty"`    // Images that will be added to the album.
+	Remove []string            `json:"remove,omitempty"` // Images that will be removed from the album.
+	Render map[string][]string `json:"render,omitempty"` // Images that will be rendered, by size.
+	Meta   []string            `json:"meta,omitempty"`   // Images that will have their metadata changed.
+
+	Subfolders []*BuildPlan `json:"subfolders,omitempty"`
+}
+
+// PlanBuild returns the plan of building a build file into the given folder
+// (see Site.Build), without building anything. If prune is set, the plan also
+// includes the folders and albums that Site.Prune would delete.
+//
+// A folder or album is created if it does not exist yet. Otherwise, it is updated
+// if its name, description or thumbnail would change, or if any image would be
+// added, removed or rendered, or have its metadata changed. Images are rendered if they are new, if their source
+// changed, or if a size's ImageScale changed since they were last rendered.
+func (s *Site) PlanBuild(b *BuildFile, folder string, prune bool) (*BuildPlan, error) {
+	p := &BuildPlan{
+		Path:   b.path(folder),
+		Type:   b.Type,
+		Action: "none",
+	}
+
+	if b.Type != "folder" && b.Type != "album" {
+		err := s.planSubfolders(p, b, prune)
+		if checkError(err) {
+			return nil, err
+		}
+
+		return p, nil
+	}
+
+	f := new(generator.Folder)
+	if s.exists(filepath.Join(p.Path, "folderInfo.json")) {
+		err := f.ReadFolderInfo(s.fsys(), filepath.Join(p.Path, "folderInfo.json"))
+		if checkError(err) {
+			return nil, err
+		}
+
+		if (b.Name != "" && f.Name != b.Name) ||
+			(b.Desc != "" && f.Desc != b.Desc) ||
+			f.Static != b.Static ||
+			(b.Type == "album" && f.Type != "album") ||
+			s.thumbChanged(f, b.Thumb, p.Path) {
+			p.Action = "update"
+		}
+	} else {
+		p.Action = "create"
+	}
+
+	if b.Type == "album" {
+		err := s.planAlbum(p, b)
+		if checkError(err) {
+			return nil, err
+		}
+
+		if p.Action == "none" && (len(p.Add) > 0 || len(p.Remove) > 0 || len(p.Render) > 0 || len(p.Meta) > 0) {
+			p.Action = "update"
+		}
+	}
+
+	err := s.planSubfolders(p, b, prune)
+	if checkError(err) {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// planSubfolders plans the subfolders of a build file into its plan.
+func (s *Site) planSubfolders(p *BuildPlan, b *BuildFile, prune bool) error {
+	for _, sf := range b.Subfolders {
+		sp, err := s.PlanBuild(sf, p.Path, prune)
+		if checkError(err) {
+			return err
+		}
+
+		p.Subfolders = append(p.Subfolders, sp)
+	}
+
+	if !prune {
+		return nil
+	}
+
+	stale, err := s.staleFolders(b, p.Path)
+	if checkError(err) {
+		return err
+	}
+
+	for _, sf := range stale {
+		f := new(generator.Folder)
+		err = f.ReadFolderInfo(s.fsys(), filepath.Join(sf, "folderInfo.json"))
+		if checkError(err) {
+			return err
+		}
+
+		p.Subfolders = append(p.Subfolders, &BuildPlan{
+			Path:   sf,
+			Type:   f.Type,
+			Action: "delete",
+		})
+	}
+
+	return nil
+}
+
+// planAlbum plans the images of an album in a build file.
+func (s *Site) planAlbum(p *BuildPlan, b *BuildFile) error {
+	images, meta, err := b.albumImages(s.fsys())
+	if checkError(err) {
+		return err
+	}
+
+	current := make(map[string]bool)
+	if s.exists(filepath.Join(p.Path, "itemsInfo.json")) {
+		items := new(generator.Items)
+		err = items.ReadItemsInfo(s.fsys(), filepath.Join(p.Path, "itemsInfo.json"))
+		if checkError(err) {
+			return err
+		}
+
+		for _, i := range items.ItemsInFolder {
+			current[i] = true
+		}
+	}
+
+	wanted := make(map[string]bool)
+	for _, i := range images {
+		wanted[filepath.Base(i)] = true
+		if !current[filepath.Base(i)] {
+			p.Add = append(p.Add, filepath.Base(i))
+		}
+	}
+
+	for i := range current {
+		if !wanted[i] {
+			p.Remove = append(p.Remove, i)
+		}
+	}
+	sort.Strings(p.Remove)
+
+	cache, err := s.openCache(p.Path)
+	if checkError(err) {
+		return err
+	}
+
+	for d, files := range groupByDir(images) {
+		ip, err := s.planImages(p.Path, d, files, cache, b.albumOptions())
+		if checkError(err) {
+			return err
+		}
+
+		for k, v := range ip.sizes {
+			if p.Render == nil {
+				p.Render = make(map[string][]string)
+			}
+
+			p.Render[k] = append(p.Render[k], v...)
+		}
+	}
+
+	for _, v := range p.Render {
+		sort.Strings(v)
+	}
+
+	for n, i := range meta {
+		_, changed, err := s.imageMeta(p.Path, i)
+		if checkError(err) {
+			return err
+		}
+
+		if changed {
+			p.Meta = append(p.Meta, n)
+		}
+	}
+	sort.Strings(p.Meta)
+
+	return nil
+}
+
+// groupByDir groups a set of files by the directory they are in.
+func groupByDir(files []string) map[string][]string {
+	dirs := make(map[string][]string)
+	for _, f := range files {
+		d := filepath.Dir(f)
+		dirs[d] = append(dirs[d], filepath.Base(f))
+	}
+
+	return dirs
+}
+
+// Print prints a plan, and the plans of its subfolders, in a readable form.
+func (p *BuildPlan) Print(w io.Writer) {
+	p.print(w, 0)
+}
+
+func (p *BuildPlan) print(w io.Writer, depth int) {
+	indent := strings.Repeat("  ", depth)
+
+	fmt.Fprintf(w, "%s%-6s %s %s\n", indent, p.Action, p.Type, p.Path)
+	if len(p.Add) > 0 {
+		fmt.Fprintf(w, "%s    add: %s\n", indent, strings.Join(p.Add, ", "))
+	}
+
+	if len(p.Remove) > 0 {
+		fmt.Fprintf(w, "%s    remove: %s\n", indent, strings.Join(p.Remove, ", "))
+	}
+
+	sizes := make([]string, 0, len(p.Render))
+	for k := range p.Render {
+		sizes = append(sizes, k)
+	}
+	sort.Strings(sizes)
+
+	for _, k := range sizes {
+		fmt.Fprintf(w, "%s    render %s: %s\n", indent, k, strings.Join(p.Render[k], ", "))
+	}
+
+	if len(p.Meta) > 0 {
+		fmt.Fprintf(w, "%s    metadata: %s\n", indent, strings.Join(p.Meta, ", "))
+	}
+
+	for _, sp := range p.Subfolders {
+		sp.print(w, depth+1)
+	}
+}
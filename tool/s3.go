@@ -0,0 +1,282 @@
+package tool
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// S3Deployer is a Deployer for a bucket in an S3-compatible object storage
+// (e.g., Amazon S3, or MinIO). Requests are signed with AWS Signature Version 4.
+type S3Deployer struct {
+	Endpoint  string // the URL of the storage (e.g., https://s3.us-east-1.amazonaws.com, or http://localhost:9000)
+	Region    string // the region of the bucket (e.g., us-east-1)
+	Bucket    string // the name of the bucket
+	Prefix    string // where the website is in the bucket, if not its root (e.g., photos)
+	PathStyle bool   // if set, the bucket is in the path of requests, rather than their host (needed for MinIO)
+
+	AccessKey string
+	SecretKey string
+
+	Client *http.Client // the client used for requests (http.DefaultClient, if nil)
+}
+
+func (d *S3Deployer) Capabilities() DeployerCapabilities {
+	return DeployerCapabilities{
+		List:   true,
+		Delete: true,
+		Hash:   "md5", // the ETag of anything not uploaded in parts
+		Atomic: true,
+	}
+}
+
+// s3Error is an error returned by an S3 API.
+type s3Error struct {
+	Code    string
+	Message string
+}
+
+// s3ListResult is the result of listing the objects in a bucket.
+type s3ListResult struct {
+	IsTruncated           bool
+	NextContinuationToken string
+	Contents              []struct {
+		Key  string
+		Size int64
+		ETag string
+	}
+}
+
+func (d *S3Deployer) List() ([]RemoteFile, error) {
+	files := make([]RemoteFile, 0)
+	prefix := d.key("")
+	token := ""
+
+	for {
+		q := url.Values{"list-type": {"2"}}
+		if prefix != "" {
+			q.Set("prefix", prefix)
+		}
+
+		if token != "" {
+			q.Set("continuation-token", token)
+		}
+
+		b, err := d.do("GET", "", q, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		r := new(s3ListResult)
+		err = xml.Unmarshal(b, r)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, c := range r.Contents {
+			files = append(files, RemoteFile{
+				Name: strings.TrimPrefix(c.Key, prefix),
+				Size: c.Size,
+				Hash: strings.Trim(c.ETag, `"`),
+			})
+		}
+
+		if !r.IsTruncated || r.NextContinuationToken == "" {
+			return files, nil
+		}
+
+		token = r.NextContinuationToken
+	}
+}
+
+func (d *S3Deployer) Upload(name string, data []byte) error {
+	_, err := d.do("PUT", d.key(name), nil, data)
+	return err
+}
+
+func (d *S3Deployer) Delete(names ...string) error {
+	for _, n := range names {
+		_, err := d.do("DELETE", d.key(n), nil, nil)
+		if err != nil {
+			return fmt.Errorf("%s: %w", n, err)
+		}
+	}
+
+	return nil
+}
+
+// key returns the key of a file in the bucket.
+func (d *S3Deployer) key(name string) string {
+	if d.Prefix == "" {
+		return name
+	}
+
+	return strings.Trim(d.Prefix, "/") + "/" + name
+}
+
+// do sends a signed request for a key in the bucket, and returns its response.
+func (d *S3Deployer) do(method string, key string, query url.Values, body []byte) ([]byte, error) {
+	u, err := url.Parse(d.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	p := "/" + key
+	if d.PathStyle {
+		p = "/" + d.Bucket + p
+	} else {
+		u.Host = d.Bucket + "." + u.Host
+	}
+
+	u.Path = path.Join(u.Path, p)
+	if strings.HasSuffix(p, "/") && !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	u.RawPath = s3Escape(u.Path, false)
+	u.RawQuery = s3Query(query)
+
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	if method == "PUT" {
+		t := mime.TypeByExtension(path.Ext(key))
+		if t == "" {
+			t = "application/octet-stream"
+		}
+
+		req.Header.Set("Content-Type", t)
+	}
+
+	d.sign(req, body, time.Now())
+
+	c := d.Client
+	if c == nil {
+		c = http.DefaultClient
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		e := new(s3Error)
+		if xml.Unmarshal(b, e) != nil || e.Code == "" {
+			return nil, fmt.Errorf("s3: %s %s: %s", method, key, resp.Status)
+		}
+
+		return nil, fmt.Errorf("s3: %s %s: %s (%s)", method, key, e.Message, e.Code)
+	}
+
+	return b, nil
+}
+
+// sign signs a request with AWS Signature Version 4, as of the given time.
+// Every header already in the request is signed, alongside its host.
+func (d *S3Deployer) sign(req *http.Request, body []byte, now time.Time) {
+	now = now.UTC()
+	date := now.Format("20060102")
+	scope := date + "/" + d.Region + "/s3/aws4_request"
+
+	payload := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Date", now.Format("20060102T150405Z"))
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payload[:]))
+
+	headers := map[string]string{"host": req.URL.Host}
+	for k, v := range req.Header {
+		headers[strings.ToLower(k)] = strings.TrimSpace(strings.Join(v, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for k := range headers {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var canonical strings.Builder
+	canonical.WriteString(req.Method + "\n")
+	canonical.WriteString(req.URL.EscapedPath() + "\n")
+	canonical.WriteString(req.URL.RawQuery + "\n")
+	for _, n := range names {
+		canonical.WriteString(n + ":" + headers[n] + "\n")
+	}
+	canonical.WriteString("\n" + strings.Join(names, ";") + "\n")
+	canonical.WriteString(hex.EncodeToString(payload[:]))
+
+	hash := sha256.Sum256([]byte(canonical.String()))
+	toSign := "AWS4-HMAC-SHA256\n" + now.Format("20060102T150405Z") + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := []byte("AWS4" + d.SecretKey)
+	for _, k := range []string{date, d.Region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, k)
+	}
+
+	req.Header.Set("Authorization", fmt.Sprintf(
+		"AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		d.AccessKey,
+		scope,
+		strings.Join(names, ";"),
+		hex.EncodeToString(hmacSHA256(key, toSign)),
+	))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3Escape escapes a string in the way that AWS Signature Version 4 expects,
+// leaving slashes alone unless escapeSlash is set.
+func s3Escape(s string, escapeSlash bool) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~',
+			c == '/' && !escapeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
+
+// s3Query encodes a query in the way that AWS Signature Version 4 expects.
+func s3Query(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for k := range query {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		for _, v := range query[k] {
+			pairs = append(pairs, s3Escape(k, true)+"="+s3Escape(v, true))
+		}
+	}
+
+	return strings.Join(pairs, "&")
+}
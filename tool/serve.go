@@ -0,0 +1,340 @@
+package tool
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"log"
+	"mime"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vulppine/fotoDen/generator"
+)
+
+// reloadPath is the path of the event stream that previewed pages listen to,
+// in order to reload themselves when the website changes.
+const reloadPath = "/__fotoDen/reload"
+
+// reloadScript is injected into every previewed web page.
+const reloadScript = `<script>new EventSource('` + reloadPath + `').onmessage = function () { location.reload() }</script>`
+
+// PreviewInterval is how often a preview server checks the website for changes.
+var PreviewInterval = 500 * time.Millisecond
+
+// Preview is an HTTP server that serves a generated fotoDen website for previewing.
+//
+// The website is served as if it were hosted at the server's own URL: the site's
+// base URL is replaced with the server's URL in every web page, script, stylesheet
+// and JSON file, and images are always served from the website itself,
+// regardless of the storage URL in config.json. Every web page also gets a script
+// injected into it that reloads the page whenever any folder info, items info,
+// image metadata, web page or theme file in the website changes.
+//
+// The base URL of the website is read from the site's configuration - if it is not
+// set there (e.g., when previewing a website without a site), it is read from the
+// root web page of the website, in the same way that fotoDen.js reads it.
+type Preview struct {
+	BaseURL string // the base URL that the website was generated with
+
+	site *Site
+	root string
+	url  string // the URL the website is being served at, without a trailing slash
+
+	mu      sync.Mutex
+	clients map[chan struct{}]bool
+	files   map[string]fileState
+	done    chan struct{}
+	close   sync.Once
+}
+
+// fileState is the state of a file, used to find out if it has changed.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+// NewPreview creates a Preview of the website in root, that is served at the given URL.
+// Watch must be called in order for pages to be reloaded when the website changes.
+func (s *Site) NewPreview(root string, u string) *Preview {
+	base := s.GeneratorConfig.WebBaseURL
+	if base == "" {
+		base = s.pageBaseURL(filepath.Join(root, "index.html"))
+	}
+
+	return &Preview{
+		BaseURL: base,
+		site:    s,
+		root:    root,
+		url:     strings.TrimSuffix(u, "/"),
+		clients: make(map[chan struct{}]bool),
+		done:    make(chan struct{}),
+	}
+}
+
+// baseURLAttr matches the base URL given to fotoDen.js in a web page.
+var baseURLAttr = regexp.MustCompile(`data-fd-baseURL="([^"]*)"`)
+
+// pageBaseURL returns the base URL that a web page in the site was generated with,
+// or nothing if it can not be read.
+func (s *Site) pageBaseURL(page string) string {
+	d, err := s.fsys().ReadFile(page)
+	if err != nil {
+		return ""
+	}
+
+	m := baseURLAttr.FindSubmatch(d)
+	if m == nil {
+		return ""
+	}
+
+	return html.UnescapeString(string(m[1]))
+}
+
+// Serve previews the website in root over HTTP, on the given address,
+// until the server fails. If baseURL is set, it replaces the base URL
+// that the website was generated with (see Preview).
+func (s *Site) Serve(root string, addr string, baseURL string) error {
+	l, err := net.Listen("tcp", addr)
+	if checkError(err) {
+		return err
+	}
+
+	p := s.NewPreview(root, "http://"+l.Addr().String())
+	if baseURL != "" {
+		p.BaseURL = baseURL
+	}
+
+	if p.BaseURL == "" {
+		log.Println("The base URL of " + root + " is not known - links to it will not be previewed")
+	}
+
+	go p.Watch()
+	defer p.Close()
+
+	fmt.Println("Serving " + root + " at " + p.url + "/")
+	return http.Serve(l, p)
+}
+
+// Close stops watching the website for changes, and disconnects every page
+// that is listening for reloads.
+func (p *Preview) Close() {
+	p.close.Do(func() { close(p.done) })
+}
+
+// Watch checks the website for changes every PreviewInterval, until the Preview
+// is closed. Every time a change is found, every page listening for reloads is reloaded.
+func (p *Preview) Watch() {
+	t := time.NewTicker(PreviewInterval)
+	defer t.Stop()
+
+	p.Check()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-t.C:
+			p.Check()
+		}
+	}
+}
+
+// Check checks the website for changes once, reloading every page
+// listening for reloads if anything changed since the last check.
+// It returns true if anything changed.
+func (p *Preview) Check() bool {
+	files := p.previewFiles()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	changed := p.files != nil && !sameFiles(p.files, files)
+	p.files = files
+	if changed {
+		verbose("preview: website changed, reloading pages")
+		for c := range p.clients {
+			select {
+			case c <- struct{}{}:
+			default:
+			}
+		}
+	}
+
+	return changed
+}
+
+// previewFiles returns every file in the website that causes pages to
+// reload when it changes.
+func (p *Preview) previewFiles() map[string]fileState {
+	files := make(map[string]fileState)
+	theme := filepath.Join(p.root, "theme")
+
+	generator.Walk(p.site.fsys(), p.root, func(name string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return nil
+		}
+
+		switch {
+		case strings.HasPrefix(name, theme+string(filepath.Separator)),
+			filepath.Ext(name) == ".json",
+			filepath.Ext(name) == ".html":
+			files[name] = fileState{info.ModTime(), info.Size()}
+		}
+
+		return nil
+	})
+
+	return files
+}
+
+// sameFiles checks if two sets of files are in the same state.
+func sameFiles(a, b map[string]fileState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for k, v := range a {
+		if w, ok := b[k]; !ok || !w.modTime.Equal(v.modTime) || w.size != v.size {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (p *Preview) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == reloadPath {
+		p.serveReload(w, r)
+		return
+	}
+
+	fsys := p.site.fsys()
+	upath := path.Clean("/" + r.URL.Path)
+	name := filepath.Join(p.root, filepath.FromSlash(upath))
+
+	f, err := fsys.Stat(name)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if f.IsDir() {
+		if !strings.HasSuffix(r.URL.Path, "/") {
+			// pages rely on relative URLs, so directories
+			// always have to end with a slash
+			http.Redirect(w, r, path.Base(upath)+"/", http.StatusMovedPermanently)
+			return
+		}
+
+		name = filepath.Join(name, "index.html")
+		f, err = fsys.Stat(name)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+	}
+
+	d, err := fsys.ReadFile(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	d, err = p.rewrite(name, d)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		w.Header().Set("Content-Type", t)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	http.ServeContent(w, r, name, f.ModTime(), bytes.NewReader(d))
+}
+
+// rewrite rewrites a file in the website so that it can be previewed.
+func (p *Preview) rewrite(name string, d []byte) ([]byte, error) {
+	ext := filepath.Ext(name)
+	switch ext {
+	case ".html", ".json", ".js", ".css":
+	default:
+		return d, nil
+	}
+
+	base := strings.TrimSuffix(p.BaseURL, "/")
+	if base != "" {
+		d = bytes.ReplaceAll(d, []byte(base), []byte(p.url))
+	}
+
+	switch {
+	case ext == ".html":
+		i := bytes.LastIndex(d, []byte("</body>"))
+		if i == -1 {
+			i = len(d)
+		}
+
+		r := make([]byte, 0, len(d)+len(reloadScript))
+		r = append(r, d[:i]...)
+		r = append(r, reloadScript...)
+		d = append(r, d[i:]...)
+	case filepath.Clean(name) == filepath.Join(p.root, "config.json"):
+		c := make(map[string]interface{})
+		err := json.Unmarshal(d, &c)
+		if checkError(err) {
+			return nil, err
+		}
+
+		c["storageURL"] = ""
+		return json.Marshal(c)
+	}
+
+	return d, nil
+}
+
+// serveReload sends an event to a page every time the website changes,
+// until either the page or the Preview is closed.
+func (p *Preview) serveReload(w http.ResponseWriter, r *http.Request) {
+	fl, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	c := make(chan struct{}, 1)
+	p.mu.Lock()
+	p.clients[c] = true
+	p.mu.Unlock()
+
+	defer func() {
+		p.mu.Lock()
+		delete(p.clients, c)
+		p.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	fl.Flush()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-p.done:
+			return
+		case <-c:
+			fmt.Fprint(w, "data: reload\n\n")
+			fl.Flush()
+		}
+	}
+}
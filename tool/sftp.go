@@ -0,0 +1,258 @@
+package tool
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// SFTPConfig is where a website is deployed to over SFTP,
+// as stored in the configuration of the website.
+type SFTPConfig struct {
+	Host    string // the host of the SSH server, and its port if it is not 22 (e.g., example.com:2222)
+	User    string // the user to log in as
+	BaseDir string // the directory that the website is uploaded into (e.g., /var/www/photos)
+}
+
+// SFTPDeployer is a Deployer for a directory on an SFTP server.
+//
+// HTML and JSON files are uploaded next to the file they replace, and then renamed
+// over it, so that pages and their information are never seen half-uploaded.
+// Every other file (e.g., images) is written in place. Servers without the
+// posix-rename@openssh.com extension can't rename over a file, so the file
+// is removed first, and is missing for a moment while it is replaced.
+type SFTPDeployer struct {
+	BaseDir string // the directory that the website is uploaded into
+
+	client *sftp.Client
+	conn   *ssh.Client
+	agent  net.Conn
+}
+
+// SSHConfig is the configuration of an SSH client (see SSHClientConfig),
+// alongside the connection to the SSH agent that it logs in with, if any.
+type SSHConfig struct {
+	*ssh.ClientConfig
+
+	agent net.Conn
+}
+
+// sftpAtomic are the extensions of the files that are replaced atomically.
+var sftpAtomic = map[string]bool{
+	".html": true,
+	".json": true,
+}
+
+// NewSFTPDeployer creates an SFTPDeployer from an SFTP client that is already connected.
+func NewSFTPDeployer(client *sftp.Client, baseDir string) *SFTPDeployer {
+	return &SFTPDeployer{BaseDir: baseDir, client: client}
+}
+
+// DialSFTP connects to an SSH server at addr (see SSHClientConfig),
+// and creates an SFTPDeployer over it. The SFTPDeployer must be closed
+// once it is no longer needed. The connection to the SSH agent in config
+// is closed alongside the SFTPDeployer, or if the server can't be connected to.
+func DialSFTP(addr string, config *SSHConfig, baseDir string) (*SFTPDeployer, error) {
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "22")
+	}
+
+	conn, err := ssh.Dial("tcp", addr, config.ClientConfig)
+	if err != nil {
+		config.closeAgent()
+		return nil, err
+	}
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		conn.Close()
+		config.closeAgent()
+		return nil, err
+	}
+
+	d := NewSFTPDeployer(client, baseDir)
+	d.conn = conn
+	d.agent = config.agent
+	return d, nil
+}
+
+// SSHClientConfig creates the configuration of an SSH client that logs in as user.
+//
+// The key in the identity file is used to log in, if one is given, as well as every key
+// in the SSH agent at $SSH_AUTH_SOCK, if there is one. The key of the server is checked
+// against the keys in the knownHosts file.
+func SSHClientConfig(user string, identity string, knownHosts string) (*SSHConfig, error) {
+	config := new(SSHConfig)
+	auth := make([]ssh.AuthMethod, 0)
+
+	if identity != "" {
+		b, err := ioutil.ReadFile(identity)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := ssh.ParsePrivateKey(b)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w (keys with a passphrase have to be added to an SSH agent)", identity, err)
+		}
+
+		auth = append(auth, ssh.PublicKeys(key))
+	}
+
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		conn, err := net.Dial("unix", sock)
+		if err != nil {
+			verbose("could not connect to the SSH agent: " + err.Error())
+		} else {
+			config.agent = conn
+			auth = append(auth, ssh.PublicKeysCallback(agent.NewClient(conn).Signers))
+		}
+	}
+
+	if len(auth) == 0 {
+		return nil, fmt.Errorf("no SSH key was given, and there is no SSH agent to use")
+	}
+
+	hostKeys, err := knownhosts.New(knownHosts)
+	if err != nil {
+		config.closeAgent()
+		return nil, err
+	}
+
+	config.ClientConfig = &ssh.ClientConfig{
+		User:            user,
+		Auth:            auth,
+		HostKeyCallback: hostKeys,
+	}
+
+	return config, nil
+}
+
+// closeAgent closes the connection to the SSH agent, if there is one.
+func (c *SSHConfig) closeAgent() {
+	if c.agent != nil {
+		c.agent.Close()
+	}
+}
+
+// Close closes the connection of the SFTPDeployer, and its connection to the SSH agent.
+func (d *SFTPDeployer) Close() error {
+	err := d.client.Close()
+	if d.conn != nil {
+		d.conn.Close()
+	}
+
+	if d.agent != nil {
+		d.agent.Close()
+	}
+
+	return err
+}
+
+func (d *SFTPDeployer) Capabilities() DeployerCapabilities {
+	return DeployerCapabilities{
+		List:   true,
+		Delete: true,
+		Atomic: d.posixRename(), // images are written in place, see sftpAtomic
+	}
+}
+
+// posixRename checks if the server can rename a file over another one.
+func (d *SFTPDeployer) posixRename() bool {
+	_, ok := d.client.HasExtension("posix-rename@openssh.com")
+	return ok
+}
+
+func (d *SFTPDeployer) List() ([]RemoteFile, error) {
+	files := make([]RemoteFile, 0)
+
+	_, err := d.client.Stat(d.BaseDir)
+	if os.IsNotExist(err) {
+		return files, nil
+	}
+
+	w := d.client.Walk(d.BaseDir)
+	for w.Step() {
+		if w.Err() != nil {
+			return nil, w.Err()
+		}
+
+		if w.Stat().IsDir() {
+			continue
+		}
+
+		name := strings.TrimPrefix(strings.TrimPrefix(w.Path(), d.BaseDir), "/")
+		files = append(files, RemoteFile{Name: name, Size: w.Stat().Size()})
+	}
+
+	return files, nil
+}
+
+func (d *SFTPDeployer) Upload(name string, data []byte) error {
+	p := path.Join(d.BaseDir, name)
+
+	err := d.client.MkdirAll(path.Dir(p))
+	if err != nil {
+		return err
+	}
+
+	if !sftpAtomic[path.Ext(p)] {
+		return d.write(p, data)
+	}
+
+	tmp := path.Join(path.Dir(p), ".fotoDen-"+path.Base(p))
+	err = d.write(tmp, data)
+	if err != nil {
+		return err
+	}
+
+	if d.posixRename() {
+		err = d.client.PosixRename(tmp, p)
+	} else {
+		err = d.client.Remove(p)
+		if err == nil || os.IsNotExist(err) {
+			err = d.client.Rename(tmp, p)
+		}
+	}
+	if err != nil {
+		d.client.Remove(tmp)
+		return err
+	}
+
+	return nil
+}
+
+// write writes data into a file on the server, replacing it if it exists.
+func (d *SFTPDeployer) write(p string, data []byte) error {
+	f, err := d.client.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (d *SFTPDeployer) Delete(names ...string) error {
+	for _, n := range names {
+		err := d.client.Remove(path.Join(d.BaseDir, n))
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("%s: %w", n, err)
+		}
+	}
+
+	return nil
+}
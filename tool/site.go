@@ -0,0 +1,144 @@
+package tool
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/vulppine/fotoDen/generator"
+)
+
+// Site represents a fotoDen website, and everything needed in order to work on it:
+// its configuration (including the generator's configuration), its theme,
+// its root location, and the default options used when generating images.
+//
+// Every operation on a website is a method of Site, so that several websites
+// can be worked on in the same process without sharing any state. The only
+// things shared by every Site are the generator's image backend and worker pool
+// (see generator.SetImageBackend and generator.SetJobs).
+type Site struct {
+	WebsiteConfig // The configuration of the website, as stored in the fotoDen config directory.
+
+	// Options are the options used by operations that are not given any options
+	// of their own, e.g., when building a site from a build file.
+	Options GeneratorOptions
+
+	// Interactive allows operations to display interactive prompts.
+	Interactive bool
+
+	// FS is the filesystem that the site is read from and generated into,
+	// as well as where source images are read from. If FS is nil, the OS
+	// filesystem is used. The site's configuration, and the fotoDen config
+	// directory, are always in the OS filesystem.
+	FS generator.FS
+
+	theme *theme
+}
+
+// NewSite creates a new Site from a WebsiteConfig.
+// If the WebsiteConfig does not have an image root directory,
+// generator.DefaultConfig is used as its generator configuration.
+func NewSite(config WebsiteConfig) *Site {
+	if config.GeneratorConfig.ImageRootDirectory == "" {
+		url := config.GeneratorConfig.WebBaseURL
+		config.GeneratorConfig = generator.DefaultConfig
+		config.GeneratorConfig.WebBaseURL = url
+	}
+
+	return &Site{WebsiteConfig: config}
+}
+
+// OpenSite opens the site with the given name from the fotoDen config directory.
+func OpenSite(name string) (*Site, error) {
+	config := new(WebsiteConfig)
+
+	err := generator.ReadJSON(generator.OSFS{}, filepath.Join(generator.RootConfigDir, "sites", name, "config.json"), config)
+	if checkError(err) {
+		return nil, err
+	}
+
+	return NewSite(*config), nil
+}
+
+// SaveConfig writes the configuration of the site into the fotoDen config directory.
+func (s *Site) SaveConfig() error {
+	if s.Name == "" {
+		return fmt.Errorf("you need to use this in conjunction with a valid fotoDen site")
+	}
+
+	return generator.WriteJSON(generator.OSFS{}, filepath.Join(generator.RootConfigDir, "sites", s.Name, "config.json"), "multi", s.WebsiteConfig)
+}
+
+// fsys returns the FS of the site.
+func (s *Site) fsys() generator.FS {
+	if s.FS == nil {
+		return generator.OSFS{}
+	}
+
+	return s.FS
+}
+
+// exists checks if a file exists in the FS of the site.
+func (s *Site) exists(name string) bool {
+	_, err := s.fsys().Stat(name)
+	return !os.IsNotExist(err)
+}
+
+// loadTheme opens the theme of the site, if it was not opened already.
+// If the site's theme is the location of a zipped theme, that theme is used -
+// otherwise, the default theme in the fotoDen config directory is used.
+func (s *Site) loadTheme() (*theme, error) {
+	if s.theme != nil {
+		return s.theme, nil
+	}
+
+	var t *theme
+	var err error
+
+	if s.Theme != "" && fileCheck(s.Theme) {
+		verbose("opening site theme: " + s.Theme)
+		z, zs, err := zipFileReader(s.Theme)
+		if checkError(err) {
+			return nil, err
+		}
+
+		t, err = openTheme(z, zs)
+		if checkError(err) {
+			return nil, err
+		}
+	} else {
+		t, err = openDefaultTheme()
+		if checkError(err) {
+			return nil, err
+		}
+	}
+
+	s.theme = t
+	return t, nil
+}
+
+// checkSite returns an error if the site is not a site
+// in the fotoDen config directory.
+func (s *Site) checkSite() error {
+	if s.Name == "" || s.RootLocation == "" {
+		return fmt.Errorf("you need to use this in conjunction with a valid fotoDen site")
+	}
+
+	return nil
+}
+
+// globalSite creates a Site from the deprecated package-level variables
+// (CurrentConfig, generator.CurrentConfig, Genoptions and WizardFlag),
+// for the functions that still use them.
+func globalSite() *Site {
+	s := new(Site)
+	if CurrentConfig != nil {
+		s.WebsiteConfig = *CurrentConfig
+	}
+
+	s.GeneratorConfig = generator.CurrentConfig
+	s.Options = Genoptions
+	s.Interactive = WizardFlag
+
+	return s
+}
@@ -0,0 +1,227 @@
+package tool
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/vulppine/fotoDen/generator"
+)
+
+// SplitDeploy is a deploy that pushes the images of a website into one remote
+// (e.g., object storage), and everything else into another (e.g., a web host).
+//
+// The manifests of a split deploy are kept apart from the manifests of pushing
+// the whole website into the same remotes (see splitManifest), so that neither
+// deletes the files that the other pushed.
+type SplitDeploy struct {
+	Pages         Deployer // where pages, information and theme files are pushed into
+	PagesRemote   string   // the name of the remote of Pages, as given to Site.Push
+	Storage       Deployer // where images are pushed into
+	StorageRemote string   // the name of the remote of Storage, as given to Site.Push
+
+	// StorageURL is the URL that Storage is reached at, which the deployed
+	// config.json of the website is rewritten to use. If empty, the storageURL
+	// in the config.json of the website is used.
+	StorageURL string
+
+	// LocalSizes are the image sizes that are kept with the pages, rather than
+	// pushed into Storage (e.g., thumbnails).
+	LocalSizes []string
+}
+
+// PushSplit pushes the website in root into the two remotes of a SplitDeploy
+// (see Site.Push).
+//
+// The sized images of every album (<album>/img/<size>/) are pushed into the storage
+// remote, along with their sources, under the same layout that makePhotoURL in
+// fotoDen.js expects: the name of the directory the website is in (the last part of
+// the path of its base URL), followed by the path of the album. Image metadata is
+// read by pages from where they are, and is kept with the pages.
+//
+// The config.json deployed with the pages has its storageURL set to the storage URL,
+// and every size that is not local is set to be read from it. Images are pushed
+// before pages, so that pages never refer to images that are not there yet - if
+// images fail to push, pages are not pushed at all. Once images are pushed, the
+// storage remote is checked for every image that fotoDen.js would look for.
+func (s *Site) PushSplit(root string, split SplitDeploy, dryRun bool) (pages *Changeset, storage *Changeset, err error) {
+	if s.GeneratorConfig.WebBaseURL == "" {
+		return nil, nil, fmt.Errorf("the site has no base URL, which is needed to find where images are in storage")
+	}
+
+	base, err := url.Parse(s.GeneratorConfig.WebBaseURL)
+	if checkError(err) {
+		return nil, nil, err
+	}
+
+	files, err := s.deployFiles(root)
+	if checkError(err) {
+		return nil, nil, err
+	}
+
+	config := new(generator.WebConfig)
+	err = config.ReadWebConfig(s.fsys(), filepath.Join(root, "config.json"))
+	if checkError(err) {
+		return nil, nil, err
+	}
+
+	storageURL := split.StorageURL
+	if storageURL == "" {
+		storageURL = config.PhotoURLBase
+	}
+
+	if storageURL == "" || storageURL == "local" {
+		return nil, nil, fmt.Errorf("a storage URL must be given, or set as storageURL in config.json")
+	}
+
+	// makePhotoURL appends the path of the album to the storage URL, which starts
+	// with a slash only if the website is at the root of its host
+	wd := workingDirectory(base.Path)
+	if wd == "" {
+		storageURL = strings.TrimSuffix(storageURL, "/")
+	} else if !strings.HasSuffix(storageURL, "/") {
+		storageURL += "/"
+	}
+
+	local := make(map[string]bool)
+	for _, l := range split.LocalSizes {
+		local[l] = true
+	}
+
+	localDirs := map[string]bool{s.GeneratorConfig.ImageMetaDirectory: true}
+	config.PhotoURLBase = storageURL
+	for i, size := range config.ImageSizes {
+		config.ImageSizes[i].LocalBool = local[size.SizeName]
+		if local[size.SizeName] {
+			localDirs[size.Directory] = true
+		}
+	}
+
+	b, err := json.MarshalIndent(config, "", "\t")
+	if checkError(err) {
+		return nil, nil, err
+	}
+
+	pageFiles := make(map[string]string)
+	storageFiles := make(map[string]string)
+	for name, file := range files {
+		album, dir, ok := s.storageDir(files, name)
+		if !ok || localDirs[dir] {
+			pageFiles[name] = file
+			continue
+		}
+
+		key := path.Join(wd, name)
+		expected := storageURL + key
+		if wd == "" {
+			expected = storageURL + "/" + key
+		}
+
+		u := photoURL(storageURL, wd, path.Join("/", base.Path, album)+"/", config.ImageRootDir+"/"+dir, path.Base(name))
+		if u != expected {
+			return nil, nil, fmt.Errorf("%s would be stored as %s, but fotoDen.js would look for it at %s", name, key, u)
+		}
+
+		storageFiles[key] = file
+	}
+
+	storage, err = s.push(split.Storage, storageFiles, nil, splitManifest("storage", split.StorageRemote), dryRun)
+	if checkError(err) {
+		return nil, storage, err
+	}
+
+	pages, err = s.push(split.Pages, pageFiles, map[string][]byte{"config.json": b}, splitManifest("pages", split.PagesRemote), dryRun)
+	if checkError(err) {
+		return pages, storage, err
+	}
+
+	if dryRun || !split.Storage.Capabilities().List {
+		return pages, storage, nil
+	}
+
+	log.Println("Checking that every image is in storage")
+	return pages, storage, checkStorage(split.Storage, storageFiles)
+}
+
+// storageDir returns the album that a file of a website is in, and the directory in
+// the image root of the album that it is in, if it is an image of an album.
+func (s *Site) storageDir(files map[string]string, name string) (string, string, bool) {
+	dir, _ := path.Split(name)
+	dir = strings.TrimSuffix(dir, "/")
+	imageRoot, sub := path.Split(dir)
+	album := strings.TrimSuffix(imageRoot, "/")
+
+	if path.Base(album) != s.GeneratorConfig.ImageRootDirectory {
+		return "", "", false
+	}
+
+	album = path.Dir(album)
+	if _, ok := files[path.Join(album, "itemsInfo.json")]; !ok {
+		return "", "", false
+	}
+
+	return album, sub, true
+}
+
+// checkStorage checks that every file in files is in a remote.
+func checkStorage(d Deployer, files map[string]string) error {
+	remote, err := d.List()
+	if checkError(err) {
+		return err
+	}
+
+	found := make(map[string]bool)
+	for _, r := range remote {
+		found[r.Name] = true
+	}
+
+	var batchErr generator.BatchError
+	for _, name := range sortedKeys(files) {
+		if !found[name] {
+			batchErr = batchErr.Append(&generator.FileError{File: name, Op: "check", Err: fmt.Errorf("not in storage")})
+		}
+	}
+
+	return batchErr.ErrorOrNil()
+}
+
+// workingDirectory returns the directory that a website is in, from the path of its
+// base URL, in the same way as readConfig in fotoDen.js.
+func workingDirectory(p string) string {
+	if p == "" || p == "/" {
+		return ""
+	}
+
+	pa := strings.Split(p, "/")
+	if pa[len(pa)-1] == "" {
+		pa = pa[:len(pa)-1]
+	}
+
+	return pa[len(pa)-1]
+}
+
+// photoURL returns the URL of a photo that is not local, as seen from the page
+// at pagePath, in the same way as makePhotoURL in fotoDen.js.
+func photoURL(storageURL string, wd string, pagePath string, dir string, name string) string {
+	pa := strings.Split(pagePath, "/")
+
+	i := len(pa) - 1 // Array.slice counts a start of -1 from the end
+	for j, p := range pa {
+		if p == wd {
+			i = j
+			break
+		}
+	}
+
+	return storageURL + strings.Join(pa[i:len(pa)-1], "/") + "/" + dir + "/" + name
+}
+
+// splitManifest returns the name of the manifest of a part (pages or storage)
+// of a split deploy into a remote (see Site.ManifestPath).
+func splitManifest(part string, remote string) string {
+	return "split-" + part + "-" + remote
+}
@@ -2,6 +2,7 @@ package tool
 
 import (
 	"archive/zip"
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -110,8 +111,8 @@ func openTheme(z io.ReaderAt, zs int64) (*theme, error) {
 }
 
 // writeFile directly writes a file from a theme's zip.Reader
-// to the given destination.
-func (t *theme) writeFile(n string, d string) error {
+// to the given destination in an FS.
+func (t *theme) writeFile(fsys generator.FS, n string, d string) error {
 	verbose("attempting to write a file from zip: " + n)
 	f, err := t.a.Open(n)
 	if checkError(err) {
@@ -122,12 +123,7 @@ func (t *theme) writeFile(n string, d string) error {
 		return err
 	}
 
-	g, err := os.Create(d)
-	if checkError(err) {
-		return err
-	}
-
-	_, err = g.Write(fb)
+	err = fsys.WriteFile(d, fb, 0644)
 	if checkError(err) {
 		return err
 	}
@@ -136,7 +132,7 @@ func (t *theme) writeFile(n string, d string) error {
 }
 
 // writeDir writes the contents of a named directory in a
-// theme's zip.Reader into the named directory d, creating
+// theme's zip.Reader into the named directory d in an FS, creating
 // a directory in the process. A list of files can be given
 // to writeDir, allowing it to skip checking the entire
 // directory.
@@ -144,7 +140,7 @@ func (t *theme) writeFile(n string, d string) error {
 // NOTE: I haven't implemented the directory read yet.
 // This is expected to be used in conjunction with
 // the theme.json setup!
-func (t *theme) writeDir(n string, d string, files ...string) error {
+func (t *theme) writeDir(fsys generator.FS, n string, d string, files ...string) error {
 	verbose("attempting to write a directory from zip: " + n)
 	i, err := t.a.Open(n)
 	if checkError(err) {
@@ -163,15 +159,15 @@ func (t *theme) writeDir(n string, d string, files ...string) error {
 		return errors.New("zip file directory checking not implemented yet")
 	}
 
-	if !fileCheck(filepath.Join(d, n)) {
-		err = os.Mkdir(filepath.Join(d, n), 0755)
+	if _, err := fsys.Stat(filepath.Join(d, n)); os.IsNotExist(err) {
+		err = fsys.Mkdir(filepath.Join(d, n), 0755)
 		if checkError(err) {
 			return err
 		}
 	}
 
 	for _, f := range files {
-		err := t.writeFile(filepath.Join(n, f), filepath.Join(d, n, f))
+		err := t.writeFile(fsys, filepath.Join(n, f), filepath.Join(d, n, f))
 		if checkError(err) {
 			return err
 		}
@@ -200,9 +196,9 @@ type webVars struct {
 	PageVars    map[string]string
 }
 
-// newWebVars creates a WebVars object. Takes a single URL string, and outputs
-// a set of fotoDen compatible URLs.
-func newWebVars(u, folder string) (*webVars, error) {
+// newWebVars creates a WebVars object. Takes an FS, a single URL string, and the folder
+// the page is in, and outputs a set of fotoDen compatible URLs.
+func newWebVars(fsys generator.FS, u, folder string) (*webVars, error) {
 
 	webvars := new(webVars)
 	url, err := url.Parse(u)
@@ -218,9 +214,10 @@ func newWebVars(u, folder string) (*webVars, error) {
 	}
 
 	f := new(generator.Folder)
-	fpath, _ := filepath.Abs(folder)
+	fpath := filepath.Clean(folder)
+	parent := filepath.Join(fpath, "..")
 
-	err = f.ReadFolderInfo(filepath.Join(fpath, "folderInfo.json"))
+	err = f.ReadFolderInfo(fsys, filepath.Join(fpath, "folderInfo.json"))
 	if err != nil {
 		return nil, err
 	}
@@ -228,7 +225,7 @@ func newWebVars(u, folder string) (*webVars, error) {
 	superFolder, err := func() (string, error) {
 		f := new(generator.Folder)
 
-		_, err := os.Stat(filepath.Join(filepath.Dir(fpath), "folderInfo.json"))
+		_, err := fsys.Stat(filepath.Join(parent, "folderInfo.json"))
 		if os.IsNotExist(err) {
 			return "", nil
 		} else if checkError(err) {
@@ -236,7 +233,7 @@ func newWebVars(u, folder string) (*webVars, error) {
 		}
 
 		verbose("Folder above is a fotoDen folder, using that...")
-		err = f.ReadFolderInfo(filepath.Join(filepath.Dir(fpath), "folderInfo.json"))
+		err = f.ReadFolderInfo(fsys, filepath.Join(parent, "folderInfo.json"))
 		if checkError(err) {
 			return "", err
 		}
@@ -276,19 +273,15 @@ const (
 
 // configurePage configures the various Go template
 // variables within a page according to a specific type.
+// fsys is the FS the page is written into,
 // u is the URL of a website,
 // d is the destination that the result goes into,
 // t is the type of page,
 // i is the set of variables to use
-func (t *theme) configurePage(u, d string, y page, i *webVars) error {
+func (t *theme) configurePage(fsys generator.FS, u, d string, y page, i *webVars) error {
 	var f string
 	p := template.New("result")
 
-	r, err := os.Create(d)
-	if err != nil {
-		return err
-	}
-
 	switch y {
 	case photo:
 		f = t.f["photo-template.html"]
@@ -305,23 +298,29 @@ func (t *theme) configurePage(u, d string, y page, i *webVars) error {
 		return err
 	}
 
-	return m.Execute(r, i)
+	var r bytes.Buffer
+	err = m.Execute(&r, i)
+	if err != nil {
+		return err
+	}
+
+	return fsys.WriteFile(d, r.Bytes(), 0644)
 }
 
-// generateWeb takes a mode, a destinatination, and an optional map[string]string.
+// generateWeb takes an FS, the URL of a website, a mode, a destinatination, and an optional map[string]string.
 // If i is not nil, that map will be merged into the WebVars PageVars field.
-func (t *theme) generateWeb(m, dest string, i map[string]string) error {
+func (t *theme) generateWeb(fsys generator.FS, u, m, dest string, i map[string]string) error {
 	var err error
 	var v *webVars
 
 	if m == "folder" || m == "album" {
-		v, err = newWebVars(generator.CurrentConfig.WebBaseURL, dest)
+		v, err = newWebVars(fsys, u, dest)
 		if err != nil {
 			return err
 		}
 	} else {
 		v = new(webVars)
-		v.BaseURL = generator.CurrentConfig.WebBaseURL
+		v.BaseURL = u
 		v.PageVars = make(map[string]string)
 	}
 
@@ -333,22 +332,22 @@ func (t *theme) generateWeb(m, dest string, i map[string]string) error {
 
 	switch m {
 	case "folder":
-		err = t.configurePage(generator.CurrentConfig.WebBaseURL, path.Join(dest, "index.html"), folder, v)
+		err = t.configurePage(fsys, u, filepath.Join(dest, "index.html"), folder, v)
 		if checkError(err) {
 			return err
 		}
 	case "album":
-		err = t.configurePage(generator.CurrentConfig.WebBaseURL, path.Join(dest, "index.html"), album, v)
+		err = t.configurePage(fsys, u, filepath.Join(dest, "index.html"), album, v)
 		if checkError(err) {
 			return err
 		}
 
-		err = t.configurePage(generator.CurrentConfig.WebBaseURL, path.Join(dest, "photo.html"), photo, v)
+		err = t.configurePage(fsys, u, filepath.Join(dest, "photo.html"), photo, v)
 		if checkError(err) {
 			return err
 		}
 	case "page":
-		err = t.configurePage(generator.CurrentConfig.WebBaseURL, dest, info, v)
+		err = t.configurePage(fsys, u, dest, info, v)
 
 		if checkError(err) {
 			return err
@@ -358,59 +357,45 @@ func (t *theme) generateWeb(m, dest string, i map[string]string) error {
 	return nil
 }
 
-/// GLOBAL THEME VAR ///
-// this probably *could* be mitigated by
-// including the current theme's name in the
-// config, allowing for functions to refer to
-// the current *website*'s theme
-
-var currentTheme *theme
-
-func setCurrentTheme(t string) error {
+// loadNamedTheme opens a theme by name from the themes directory
+// in the user's config directory. The theme named Default is the
+// theme embed into fotoDen during build.
+func loadNamedTheme(t string) (*theme, error) {
 	c, err := os.UserConfigDir()
 	if checkError(err) {
-		return err
+		return nil, err
 	}
 
 	if t != "Default" {
 		p := filepath.Join(c, "fotoDen", "themes", t+".zip")
 		z, s, err := zipFileReader(p)
 		if checkError(err) {
-			return err
+			return nil, err
 		}
 
-		currentTheme, err = openTheme(z, s)
-		if checkError(err) {
-			return err
-		}
-	} else {
-		if isEmbed {
-			currentTheme, _ = openTheme(defaultThemeZipReader(), defaultThemeZipLen)
-		} else {
-			return fmt.Errorf("could not find a fotoDen theme to use")
-		}
+		return openTheme(z, s)
 	}
 
-	return nil
+	if isEmbed {
+		return openTheme(defaultThemeZipReader(), defaultThemeZipLen)
+	}
+
+	return nil, fmt.Errorf("could not find a fotoDen theme to use")
 }
 
-func openDefaultTheme() error {
-	if fileCheck(path.Join(generator.RootConfigDir, "defaulttheme")) {
-		f, err := os.Open(path.Join(generator.RootConfigDir, "defaulttheme"))
-		d, err := ioutil.ReadAll(f)
-		if checkError(err) {
-			return err
-		}
+// openDefaultTheme opens the theme named in the defaulttheme file
+// of the fotoDen config directory.
+func openDefaultTheme() (*theme, error) {
+	if !fileCheck(path.Join(generator.RootConfigDir, "defaulttheme")) {
+		return nil, fmt.Errorf("warning: could not find a default fotoDen theme to use")
+	}
 
-		err = setCurrentTheme(string(d))
-		if checkError(err) {
-			return err
-		}
-	} else {
-		return fmt.Errorf("warning: could not find a default fotoDen theme to use")
+	d, err := ioutil.ReadFile(path.Join(generator.RootConfigDir, "defaulttheme"))
+	if checkError(err) {
+		return nil, err
 	}
 
-	return nil
+	return loadNamedTheme(string(d))
 }
 
 // initTheme initializes a theme to a site, replacing
@@ -418,8 +403,8 @@ func openDefaultTheme() error {
 // needs in order to generate a gallery.
 // u is the URL of the site
 // e is the template directory of the site
-// r is the root directory of the site
-func (t *theme) initTheme(u string, e string, r string) error {
+// r is the root directory of the site, in fsys
+func (t *theme) initTheme(fsys generator.FS, u string, e string, r string) error {
 	var err error
 	verbose("attempting to initialize a theme")
 	/*m, err := os.MkdirTemp("", "")
@@ -460,7 +445,7 @@ func (t *theme) initTheme(u string, e string, r string) error {
 
 	copyArray := func(f []string, n string) error {
 		if len(f) != 0 {
-			err = t.writeDir(n, r, f...)
+			err = t.writeDir(fsys, n, r, f...)
 			if checkError(err) {
 				return err
 			}
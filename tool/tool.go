@@ -2,7 +2,7 @@
 package tool
 
 import (
-	"io/ioutil"
+	"errors"
 	"log"
 	"os"
 	"path/filepath"
@@ -11,6 +11,8 @@ import (
 )
 
 // WizardFlag specifies if fotoDen tool functions should have interactive input or not.
+//
+// Deprecated: set Site.Interactive instead.
 var WizardFlag bool
 
 // NameFlag sets the name for a folder/album. If this is not set, fotoDen will automatically use the folder's name.
@@ -23,10 +25,10 @@ var Recurse bool
 var URLFlag string
 
 // CurrentConfig represents the current site configuration file being used by fotoDen's tool.
+//
+// Deprecated: use a Site instead.
 var CurrentConfig *WebsiteConfig
 
-// TODO: Find everything that uses the Genoptions global var and *refactor*
-
 // GeneratorOptions is a set of options for the generator.
 //
 // Includes:
@@ -47,6 +49,8 @@ type GeneratorOptions struct {
 }
 
 // Genoptions is a global variable for functions that use GeneratorOptions.
+//
+// Deprecated: set Site.Options instead.
 var Genoptions GeneratorOptions
 
 func checkError(err error) bool {
@@ -58,6 +62,15 @@ func checkError(err error) bool {
 	return false
 }
 
+// isBatchError checks if an error is a generator.BatchError - in other words,
+// if some files failed during a batch operation, but the operation itself
+// still finished. These errors are returned after everything else is done,
+// rather than stopping whatever is currently being done.
+func isBatchError(err error) bool {
+	var b generator.BatchError
+	return errors.As(err, &b)
+}
+
 // Verbose toggles the verbosity of the command line tool.
 var Verbose bool
 
@@ -82,36 +95,30 @@ type fvisitFunction func(string) error
 // by seeing if a folder contains a folderInfo.json.
 // If it does not, it terminates
 //
-// TODO: Replace this with the new fs library function in Go 1.16
+// Deprecated: use Site.RecursiveVisit instead.
 func RecursiveVisit(folder string, fn fvisitFunction) error {
-	wd, err := os.Getwd()
-	if checkError(err) {
-		return err
-	}
+	return new(Site).RecursiveVisit(folder, fn)
+}
 
-	if !fileCheck(filepath.Join(folder, "folderInfo.json")) {
+// RecursiveVisit recursively visits the fotoDen folders in the FS of the site,
+// and performs a function inside of them. See RecursiveVisit for more information.
+func (s *Site) RecursiveVisit(folder string, fn fvisitFunction) error {
+	if !s.exists(filepath.Join(folder, "folderInfo.json")) {
 		return nil
 	}
 
-	err = fn(folder)
+	err := fn(folder)
 	if checkError(err) {
 		return err
 	}
 
-	folders, err := ioutil.ReadDir(folder)
-	if checkError(err) {
-		return err
-	}
-
-	defer os.Chdir(wd)
-
-	err = os.Chdir(folder)
+	folders, err := s.fsys().ReadDir(folder)
 	if checkError(err) {
 		return err
 	}
 
 	for _, f := range generator.GetArrayOfFolders(folders) {
-		err = RecursiveVisit(f, fn)
+		err = s.RecursiveVisit(filepath.Join(folder, f), fn)
 		if checkError(err) {
 			return err
 		}
@@ -0,0 +1,504 @@
+package tool
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/vulppine/fotoDen/generator"
+	"gopkg.in/yaml.v2"
+)
+
+//go:embed schema/*.schema.json
+var schemas embed.FS
+
+// Schema returns the published JSON Schema of a kind of fotoDen file.
+// See SchemaNames for every kind of file that has a schema.
+func Schema(name string) ([]byte, error) {
+	d, err := schemas.ReadFile("schema/" + name + ".schema.json")
+	if err != nil {
+		return nil, fmt.Errorf("there is no schema named %s", name)
+	}
+
+	return d, nil
+}
+
+// SchemaNames returns the name of every published JSON Schema:
+// buildfile (build files), folderInfo, itemsInfo, imageMeta (the metadata of an image),
+// config (the config.json in the root of a website), and site (the configuration
+// of a website in the fotoDen config directory).
+func SchemaNames() []string {
+	files, _ := schemas.ReadDir("schema")
+
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, strings.TrimSuffix(f.Name(), ".schema.json"))
+	}
+
+	sort.Strings(names)
+	return names
+}
+
+// Problem is a problem found while validating a file.
+// If the line that caused the problem is known, Line is set.
+type Problem struct {
+	File    string `json:"file"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func (p Problem) String() string {
+	if p.Line != 0 {
+		return fmt.Sprintf("%s:%d: %s", p.File, p.Line, p.Message)
+	}
+
+	return p.File + ": " + p.Message
+}
+
+// yamlLine matches the line that a YAML error was caused by.
+var yamlLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// yamlUnknownField matches a YAML error about an unknown field, in order to
+// word it the same way as the JSON Schema errors about unknown fields.
+var yamlUnknownField = regexp.MustCompile(`^field (\S+) not found in type \S+$`)
+
+// yamlProblems turns an error from decoding a YAML file into problems,
+// one for every line that caused it.
+func yamlProblems(file string, err error) []Problem {
+	msgs := []string{err.Error()}
+
+	var typeErr *yaml.TypeError
+	if errors.As(err, &typeErr) {
+		msgs = typeErr.Errors
+	}
+
+	problems := make([]Problem, 0, len(msgs))
+	for _, m := range msgs {
+		p := Problem{File: file, Message: m}
+		if l := yamlLine.FindStringSubmatch(m); l != nil {
+			p.Line, _ = strconv.Atoi(l[1])
+			p.Message = l[2]
+		}
+
+		if f := yamlUnknownField.FindStringSubmatch(p.Message); f != nil {
+			p.Message = "unknown field " + f[1]
+		}
+
+		problems = append(problems, p)
+	}
+
+	return problems
+}
+
+// ValidateBuildYAML validates a build file, and every build file it includes.
+//
+// Every build file is checked against the published JSON Schema of build files
+// (see Schema), and then every folder/album in it is checked for images, image
+// directories and thumbnails that do not exist, patterns that do not match any
+// image, and albums without any images. Problems are reported under the name
+// of the build file they are in, rather than the build file including it.
+// An error is only returned if the build file itself could not be read.
+func (s *Site) ValidateBuildYAML(file string) ([]Problem, error) {
+	d, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	problems := s.validateBuildFile(file, d, "", nil)
+	if len(problems) > 0 {
+		return problems, nil
+	}
+
+	// presets that do not exist are only found once every build file is resolved
+	err = new(BuildFile).OpenBuildYAML(file)
+	if err != nil {
+		var fileErr *BuildFileError
+		if !errors.As(err, &fileErr) {
+			return nil, err
+		}
+
+		return yamlProblems(fileErr.File, fileErr.Err), nil
+	}
+
+	return problems, nil
+}
+
+// validateBuildFile validates a single build file, with its contents in d, and every
+// build file it includes. Folder is the directory of the entry that includes it, and
+// files is every build file including it, in order to catch build files that include
+// themselves.
+func (s *Site) validateBuildFile(file string, d []byte, folder string, files []string) []Problem {
+	abs, err := filepath.Abs(file)
+	if err != nil {
+		return []Problem{{File: file, Message: err.Error()}}
+	}
+
+	for _, f := range files {
+		if f == abs {
+			return []Problem{{File: file, Message: "build file includes itself"}}
+		}
+	}
+
+	// only unknown fields and values of the wrong type have their line known,
+	// so they are checked for before the rest of the schema
+	err = yaml.UnmarshalStrict(d, new(BuildFile))
+	if err != nil {
+		return yamlProblems(file, err)
+	}
+
+	m := make(yamlMap)
+	err = yaml.Unmarshal(d, &m)
+	if err != nil {
+		return yamlProblems(file, err)
+	}
+
+	if problems := schemaProblems(file, "buildfile", yamlValue(m), nil); len(problems) > 0 {
+		return problems
+	}
+
+	if len(files) > 0 {
+		relocateBuildEntry(m, filepath.Dir(file))
+	}
+
+	b := new(BuildFile)
+	if r, err := yaml.Marshal(m); err != nil || yaml.Unmarshal(r, b) != nil {
+		return []Problem{{File: file, Message: "could not be read as a build file"}}
+	}
+
+	return s.validateBuild(file, b, folder, append(files, abs))
+}
+
+// validateBuild validates a folder/album in a build file, and every folder/album under it.
+func (s *Site) validateBuild(file string, b *BuildFile, folder string, files []string) []Problem {
+	problems := make([]Problem, 0)
+	dir := b.path(folder)
+
+	add := func(format string, a ...interface{}) {
+		problems = append(problems, Problem{File: file, Message: dir + ": " + fmt.Sprintf(format, a...)})
+	}
+
+	if b.Thumb != "" && !s.exists(b.Thumb) {
+		add("thumbnail %s does not exist", b.Thumb)
+	}
+
+	checkExclude := func(exclude []string) {
+		for _, e := range exclude {
+			if _, err := filepath.Match(e, ""); err != nil {
+				add("bad exclude pattern %s: %s", e, err)
+			}
+		}
+	}
+
+	checkExclude(b.Exclude)
+
+	presets := make([]string, 0, len(b.Defaults))
+	for k := range b.Defaults {
+		presets = append(presets, k)
+	}
+	sort.Strings(presets)
+
+	for _, k := range presets {
+		p := b.Defaults[k]
+		if p.Thumb != "" && !s.exists(p.Thumb) {
+			add("thumbnail %s of preset %s does not exist", p.Thumb, k)
+		}
+
+		checkExclude(p.Exclude)
+	}
+
+	if b.Type != "album" {
+		if b.ImageDir != "" || len(b.Images) > 0 {
+			add("only albums can have images")
+		}
+	} else {
+		if b.ImageDir == "" && len(b.Images) == 0 {
+			add("album has no images")
+		}
+
+		if b.ImageDir != "" {
+			if st, err := s.fsys().Stat(b.ImageDir); err != nil || !st.IsDir() {
+				add("image directory %s does not exist", b.ImageDir)
+			}
+		}
+
+		for _, i := range b.Images {
+			p := i.File
+			if b.ImageDir != "" && !filepath.IsAbs(p) {
+				p = filepath.Join(b.ImageDir, p)
+			}
+
+			files, err := glob(s.fsys(), p)
+			switch {
+			case err != nil:
+				add("%s", err)
+			case len(files) == 0:
+				add("%s does not match any images", i.File)
+			case len(files) == 1 && files[0] == p && !s.exists(p):
+				add("image %s does not exist", i.File)
+			}
+		}
+	}
+
+	for _, sf := range b.Subfolders {
+		problems = append(problems, s.validateBuild(file, sf, dir, files)...)
+	}
+
+	for _, inc := range b.Include {
+		p := inc
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(filepath.Dir(file), p)
+		}
+
+		d, err := ioutil.ReadFile(p)
+		if err != nil {
+			add("%s", err)
+			continue
+		}
+
+		problems = append(problems, s.validateBuildFile(p, d, dir, files)...)
+	}
+
+	return problems
+}
+
+// schemaProblems validates a value read from a file against the published JSON Schema
+// with the given name, and returns every error as a problem. Lines is the line of every
+// value in the file, by its path (see decodeJSONLines), if it is known.
+func schemaProblems(file string, schema string, v interface{}, lines map[string]int) []Problem {
+	errs, err := validateSchema(schema, v)
+	if err != nil {
+		return []Problem{{File: file, Message: err.Error()}}
+	}
+
+	problems := make([]Problem, 0, len(errs))
+	for _, e := range errs {
+		problems = append(problems, Problem{File: file, Line: lines[e.Path], Message: e.Message})
+	}
+
+	return problems
+}
+
+// validateJSON validates a JSON file in fsys against the published JSON Schema with the
+// given name, and decodes it into v. Whatever stopped the file from being valid is returned
+// as problems, alongside if the file could still be decoded.
+func validateJSON(fsys generator.FS, file string, schema string, v interface{}) ([]Problem, bool) {
+	d, err := fsys.ReadFile(file)
+	if err != nil {
+		return []Problem{{File: file, Message: err.Error()}}, false
+	}
+
+	value, lines, err := decodeJSONLines(d)
+	if err == nil {
+		problems := schemaProblems(file, schema, value, lines)
+
+		err = json.Unmarshal(d, v)
+		if err == nil {
+			return problems, true
+		}
+	}
+
+	// the line is only known for syntax and type errors
+	p := Problem{File: file, Message: err.Error()}
+
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	}
+
+	if offset > 0 && offset <= int64(len(d)) {
+		p.Line = bytes.Count(d[:offset], []byte("\n")) + 1
+	}
+
+	return []Problem{p}, false
+}
+
+// ValidateConfig validates the configuration of the site in the fotoDen config directory.
+//
+// Besides being checked against the published JSON Schema of site configurations
+// (see Schema), every size in the generator's configuration is checked for
+// ImageScale settings that can not be generated: sizes without any scaling, and
+// formats that are unknown or unsupported by the current image backend.
+// Sizes that would be generated into the source or metadata directory of an album
+// are also reported.
+func (s *Site) ValidateConfig() []Problem {
+	file := filepath.Join(generator.RootConfigDir, "sites", s.Name, "config.json")
+
+	config := new(WebsiteConfig)
+	problems, ok := validateJSON(generator.OSFS{}, file, "site", config)
+	if !ok {
+		return problems
+	}
+
+	add := func(format string, a ...interface{}) {
+		problems = append(problems, Problem{File: file, Message: fmt.Sprintf(format, a...)})
+	}
+
+	g := config.GeneratorConfig
+	if g.ImageRootDirectory == "" {
+		add("ImageRootDirectory is not set")
+	}
+
+	sizes := make([]string, 0, len(g.ImageSizes))
+	for k := range g.ImageSizes {
+		sizes = append(sizes, k)
+	}
+	sort.Strings(sizes)
+
+	for _, k := range sizes {
+		v := g.ImageSizes[k]
+
+		if k == g.ImageSrcDirectory || k == g.ImageMetaDirectory {
+			add("size %s would be generated into the %s directory of every album", k, k)
+		}
+
+		if v.MaxHeight == 0 && v.MaxWidth == 0 && v.ScalePercent == 0 {
+			add("size %s has no MaxHeight, MaxWidth or ScalePercent set", k)
+		}
+
+		for _, f := range v.Formats {
+			if _, err := generator.ImageFormat(f); err != nil {
+				add("size %s: %s", k, strings.TrimPrefix(err.Error(), "ImageFormat: "))
+			}
+		}
+	}
+
+	return problems
+}
+
+// ValidateSite validates every folder and album in a folder of the site
+// (and the config.json of the website, if the folder is the root of the website).
+//
+// Every folderInfo.json, itemsInfo.json and image metadata file is checked against
+// its published JSON Schema (see Schema). Folders are checked for subfolders
+// that do not exist, and albums are checked for items that are missing their
+// sizes or metadata, or whose amount does not match the album's itemAmount.
+func (s *Site) ValidateSite(folder string) ([]Problem, error) {
+	problems := make([]Problem, 0)
+
+	if s.exists(filepath.Join(folder, "config.json")) {
+		problems = append(problems, s.validateWebConfig(filepath.Join(folder, "config.json"))...)
+	}
+
+	if !s.exists(filepath.Join(folder, "folderInfo.json")) {
+		return nil, fmt.Errorf("%s is not a fotoDen folder", folder)
+	}
+
+	err := s.RecursiveVisit(folder, func(f string) error {
+		problems = append(problems, s.validateFolder(f)...)
+		return nil
+	})
+	if checkError(err) {
+		return nil, err
+	}
+
+	return problems, nil
+}
+
+// validateWebConfig validates the config.json of a website.
+func (s *Site) validateWebConfig(file string) []Problem {
+	config := new(generator.WebConfig)
+	problems, ok := validateJSON(s.fsys(), file, "config", config)
+	if !ok {
+		return problems
+	}
+
+	sizes := make(map[string]bool)
+	for _, i := range config.ImageSizes {
+		sizes[i.SizeName] = true
+	}
+
+	check := func(field string, size string) {
+		if size != "" && !sizes[size] {
+			problems = append(problems, Problem{File: file, Message: fmt.Sprintf("%s is %s, which is not in imageSizes", field, size)})
+		}
+	}
+
+	check("thumbnailSize", config.ThumbnailFrom)
+	check("displayImageSize", config.DisplayImageFrom)
+	for _, d := range config.DownloadSizes {
+		check("a downloadable size", d)
+	}
+
+	return problems
+}
+
+// validateFolder validates a single folder or album.
+func (s *Site) validateFolder(folder string) []Problem {
+	file := filepath.Join(folder, "folderInfo.json")
+
+	f := new(generator.Folder)
+	problems, ok := validateJSON(s.fsys(), file, "folderInfo", f)
+	if !ok {
+		return problems
+	}
+
+	add := func(file string, format string, a ...interface{}) {
+		problems = append(problems, Problem{File: file, Message: fmt.Sprintf(format, a...)})
+	}
+
+	for _, sf := range f.Subfolders {
+		if !s.exists(filepath.Join(folder, sf, "folderInfo.json")) {
+			add(file, "subfolder %s is not a fotoDen folder", sf)
+		}
+	}
+
+	if f.Type != "album" {
+		return problems
+	}
+
+	file = filepath.Join(folder, "itemsInfo.json")
+	items := new(generator.Items)
+	p, ok := validateJSON(s.fsys(), file, "itemsInfo", items)
+	problems = append(problems, p...)
+	if !ok {
+		return problems
+	}
+
+	if f.ItemAmount != len(items.ItemsInFolder) {
+		add(filepath.Join(folder, "folderInfo.json"), "itemAmount is %d, but the album has %d items", f.ItemAmount, len(items.ItemsInFolder))
+	}
+
+	imageRoot := filepath.Join(folder, s.GeneratorConfig.ImageRootDirectory)
+	metaDir := filepath.Join(imageRoot, s.GeneratorConfig.ImageMetaDirectory)
+
+	sizes := make([]string, 0, len(s.GeneratorConfig.ImageSizes))
+	for k := range s.GeneratorConfig.ImageSizes {
+		sizes = append(sizes, k)
+	}
+	sort.Strings(sizes)
+
+	for _, i := range items.ItemsInFolder {
+		for _, k := range sizes {
+			if !s.exists(filepath.Join(imageRoot, k, generator.SizedImageName(k, i, ""))) {
+				add(file, "%s is missing its %s size", i, k)
+			}
+		}
+
+		if !items.Metadata {
+			continue
+		}
+
+		if !s.exists(filepath.Join(metaDir, i+".json")) {
+			add(file, "%s is missing its metadata", i)
+			continue
+		}
+
+		p, _ := validateJSON(s.fsys(), filepath.Join(metaDir, i+".json"), "imageMeta", new(generator.ImageMeta))
+		problems = append(problems, p...)
+	}
+
+	return problems
+}
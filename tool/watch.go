@@ -0,0 +1,332 @@
+package tool
+
+import (
+	"log"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/vulppine/fotoDen/generator"
+)
+
+// Watcher watches the source directories of albums in a site, and updates
+// the albums whenever images in their sources are added, changed or deleted.
+//
+// Added and changed images are put into their album through Site.AddImages,
+// and deleted images are removed through Site.DeleteImages, in the same way
+// as if they were added or deleted by hand. Changes are only applied once a
+// source has stayed the same for Debounce, so that images that are still
+// being written (e.g., by an editor exporting them) are not picked up halfway.
+//
+// Only changes made after an album is added to a Watcher are picked up -
+// use Site.UpdateImages to sync an album with changes made before that.
+type Watcher struct {
+	Interval time.Duration // how often sources are checked for changes
+	Debounce time.Duration // how long a source has to stay the same before its changes are applied
+
+	site   *Site
+	albums []*watchedAlbum
+}
+
+// watchedAlbum is an album in a Watcher, alongside the state of its source.
+type watchedAlbum struct {
+	folder  string
+	source  string
+	options GeneratorOptions
+	build   *BuildFile // if set, only the images it declares are watched, and they are given its metadata
+
+	files   map[string]fileState   // the images in the source, as of the last applied change
+	seen    map[string]fileState   // the images in the source, as of the last check
+	changed time.Time              // when seen last changed
+	checked map[string]checkedFile // every file checked to be an image or not, so it is not read again
+}
+
+// checkedFile is a file that was checked to be an image or not.
+type checkedFile struct {
+	state fileState
+	image bool
+}
+
+// NewWatcher creates a new Watcher for the site, without any albums.
+func (s *Site) NewWatcher() *Watcher {
+	return &Watcher{
+		Interval: time.Second,
+		Debounce: 2 * time.Second,
+		site:     s,
+	}
+}
+
+// Add adds an album to the Watcher, which is updated whenever its source changes.
+func (w *Watcher) Add(folder string, source string, options GeneratorOptions) error {
+	return w.add(&watchedAlbum{
+		folder:  folder,
+		source:  source,
+		options: options,
+	})
+}
+
+func (w *Watcher) add(a *watchedAlbum) error {
+	a.checked = make(map[string]checkedFile)
+
+	files, err := w.scan(a)
+	if checkError(err) {
+		return err
+	}
+
+	a.files = files
+	a.seen = files
+	w.albums = append(w.albums, a)
+
+	verbose("Watching " + a.source + " for album " + a.folder)
+	return nil
+}
+
+// AddAlbums adds every album in a folder (and its subfolders) to the Watcher,
+// using the source that each album was last generated or synced from.
+// Albums without a recorded source are skipped.
+func (w *Watcher) AddAlbums(folder string, options GeneratorOptions) error {
+	return w.site.RecursiveVisit(folder, func(f string) error {
+		if !w.site.exists(filepath.Join(f, "itemsCache.json")) {
+			return nil
+		}
+
+		cache, err := w.site.openCache(f)
+		if checkError(err) {
+			return err
+		}
+
+		if cache.Source == "" {
+			verbose("Album " + f + " has no recorded source, skipping")
+			return nil
+		}
+
+		return w.Add(f, cache.Source, options)
+	})
+}
+
+// AddBuild adds every album in a build file to the Watcher, as if the build file
+// was built into the given folder. The image directory of an album, and the
+// directories of its individual images (or glob patterns), are watched for the
+// images that the build file declares. Images excluded by the build file are
+// ignored, and images with metadata in the build file are given it when they are
+// added or changed.
+func (w *Watcher) AddBuild(b *BuildFile, folder string) error {
+	dir := b.path(folder)
+
+	if b.Type == "album" {
+		sources := b.imageDirs()
+		if len(sources) == 0 {
+			log.Printf("Album %s has no images in the build file, not watching it", dir)
+		}
+
+		for _, src := range sources {
+			err := w.add(&watchedAlbum{
+				folder:  dir,
+				source:  src,
+				options: b.albumOptions(),
+				build:   b,
+			})
+			if checkError(err) {
+				return err
+			}
+		}
+	}
+
+	for _, f := range b.Subfolders {
+		err := w.AddBuild(f, dir)
+		if checkError(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Watch checks every album's source for changes every Interval, until done is closed.
+// Errors while updating albums are logged, rather than stopping the Watcher.
+func (w *Watcher) Watch(done <-chan struct{}) {
+	t := time.NewTicker(w.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-t.C:
+			err := w.Check()
+			if err != nil {
+				log.Println(err)
+			}
+		}
+	}
+}
+
+// Check checks every album's source for changes once, and applies
+// the changes of every source that has stayed the same for Debounce.
+// Images that fail to generate are returned together as a generator.BatchError,
+// once every album is checked.
+func (w *Watcher) Check() error {
+	return w.check(time.Now())
+}
+
+func (w *Watcher) check(now time.Time) error {
+	var batchErr generator.BatchError
+
+	for _, a := range w.albums {
+		files, err := w.scan(a)
+		if checkError(err) {
+			return err
+		}
+
+		if !sameFiles(files, a.seen) {
+			a.seen = files
+			a.changed = now
+		}
+
+		if sameFiles(a.files, a.seen) || now.Sub(a.changed) < w.Debounce {
+			continue
+		}
+
+		err = w.apply(a)
+		if checkError(err) {
+			if !isBatchError(err) {
+				return err
+			}
+
+			batchErr = batchErr.Append(err)
+		}
+	}
+
+	return batchErr.ErrorOrNil()
+}
+
+// scan returns the state of every image in the source of a watched album.
+func (w *Watcher) scan(a *watchedAlbum) (map[string]fileState, error) {
+	fsys := w.site.fsys()
+
+	dir, err := fsys.ReadDir(a.source)
+	if checkError(err) {
+		return nil, err
+	}
+
+	states := make(map[string]fileState)
+	unchecked := make([]string, 0)
+
+	for _, f := range dir {
+		if f.IsDir() {
+			continue
+		}
+
+		st := fileState{f.ModTime(), f.Size()}
+		states[f.Name()] = st
+
+		if c, ok := a.checked[f.Name()]; !ok || c.state != st {
+			unchecked = append(unchecked, f.Name())
+		}
+	}
+
+	images := make(map[string]bool)
+	for _, f := range generator.IsolateImages(fsys, a.source, unchecked) {
+		images[f] = true
+	}
+
+	for _, n := range unchecked {
+		a.checked[n] = checkedFile{states[n], images[n]}
+	}
+
+	files := make(map[string]fileState)
+	for n, c := range a.checked {
+		if _, ok := states[n]; !ok {
+			delete(a.checked, n)
+		} else if c.image && (a.build == nil || a.build.declares(filepath.Join(a.source, n))) {
+			files[n] = c.state
+		}
+	}
+
+	return files, nil
+}
+
+// apply applies the changes made to the source of a watched album since
+// the last time its changes were applied. If they fail to apply, they
+// are applied again the next time the album is checked.
+func (w *Watcher) apply(a *watchedAlbum) error {
+	added := make([]string, 0)
+	deleted := make([]string, 0)
+
+	for n, st := range a.seen {
+		if f, ok := a.files[n]; !ok || f != st {
+			added = append(added, filepath.Join(a.source, n))
+		}
+	}
+
+	for n := range a.files {
+		if _, ok := a.seen[n]; !ok {
+			deleted = append(deleted, n)
+		}
+	}
+
+	sort.Strings(added)
+	sort.Strings(deleted)
+
+	if len(deleted) > 0 {
+		log.Printf("Deleting %d image(s) from %s", len(deleted), a.folder)
+		err := w.site.DeleteImages(a.folder, deleted...)
+		if checkError(err) {
+			return err
+		}
+
+		// recorded as soon as they are deleted, so that they are not deleted again
+		files := make(map[string]fileState, len(a.files))
+		for n, st := range a.files {
+			if _, ok := a.seen[n]; ok {
+				files[n] = st
+			}
+		}
+		a.files = files
+	}
+
+	if len(added) > 0 {
+		log.Printf("Adding %d image(s) to %s", len(added), a.folder)
+		mode := "append"
+		if a.options.Sort {
+			mode = "sort"
+		}
+
+		err := w.site.AddImages(a.folder, mode, a.options, added...)
+		if checkError(err) {
+			return err
+		}
+
+		err = w.writeImageMeta(a, added)
+		if checkError(err) {
+			return err
+		}
+	}
+
+	// only recorded once they are applied, so that changes that fail are tried again
+	a.files = a.seen
+	return nil
+}
+
+// writeImageMeta writes the metadata that the build file of a watched album
+// gives to any of the images that were just added to it (see Site.writeImageMeta).
+func (w *Watcher) writeImageMeta(a *watchedAlbum, added []string) error {
+	if a.build == nil {
+		return nil
+	}
+
+	_, meta, err := a.build.albumImages(w.site.fsys())
+	if checkError(err) {
+		return err
+	}
+
+	addedMeta := make(map[string]*BuildImage)
+	for _, i := range added {
+		n := filepath.Base(i)
+		if m, ok := meta[n]; ok {
+			addedMeta[n] = m
+		}
+	}
+
+	return w.site.writeImageMeta(a.folder, addedMeta)
+}
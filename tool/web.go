@@ -23,32 +23,37 @@ import (
 // It is up to the fotoDen tool to copy over the relevant files,
 // and folder configuration.
 func GenerateWebRoot(fpath string) error {
-	err := os.Mkdir(fpath, 0755)
+	return generateWebRoot(generator.OSFS{}, fpath)
+}
+
+// generateWebRoot generates the root of a fotoDen website in fpath, in an FS.
+func generateWebRoot(fsys generator.FS, fpath string) error {
+	err := fsys.Mkdir(fpath, 0755)
 	if err != nil {
 		return err
 	}
 
-	err = os.Mkdir(filepath.Join(fpath, "js"), 0755)
+	err = fsys.Mkdir(filepath.Join(fpath, "js"), 0755)
 	if err != nil {
 		return err
 	}
 
-	err = os.Mkdir(filepath.Join(fpath, "theme"), 0755)
+	err = fsys.Mkdir(filepath.Join(fpath, "theme"), 0755)
 	if err != nil {
 		return err
 	}
 
-	err = os.Mkdir(filepath.Join(fpath, "theme", "js"), 0755)
+	err = fsys.Mkdir(filepath.Join(fpath, "theme", "js"), 0755)
 	if err != nil {
 		return err
 	}
 
-	err = os.Mkdir(filepath.Join(fpath, "theme", "css"), 0755)
+	err = fsys.Mkdir(filepath.Join(fpath, "theme", "css"), 0755)
 	if err != nil {
 		return err
 	}
 
-	err = os.Mkdir(filepath.Join(fpath, "theme", "etc"), 0755)
+	err = fsys.Mkdir(filepath.Join(fpath, "theme", "etc"), 0755)
 	if err != nil {
 		return err
 	}
@@ -58,28 +63,29 @@ func GenerateWebRoot(fpath string) error {
 
 // MakeAlbumDirectoryStructure makes a fotoDen-suitable album structure in the given rootDirectory (string).
 // The directory must exist beforehand.
+//
+// Deprecated: use Site.MakeAlbumDirectoryStructure instead.
 func MakeAlbumDirectoryStructure(rootDirectory string) error {
+	return globalSite().MakeAlbumDirectoryStructure(rootDirectory)
+}
 
-	currentDirectory, _ := os.Getwd()
-
-	defer func() {
-		verbose("Changing back to " + currentDirectory)
-		os.Chdir(currentDirectory)
-	}()
-
-	verbose("Attempting to change to " + rootDirectory)
-	err := os.Chdir(rootDirectory)
-	if err != nil {
+// MakeAlbumDirectoryStructure makes a fotoDen-suitable album structure in the given rootDirectory (string),
+// according to the site's generator configuration. The directory must exist beforehand.
+func (s *Site) MakeAlbumDirectoryStructure(rootDirectory string) error {
+	fsys := s.fsys()
+	if _, err := fsys.Stat(rootDirectory); err != nil {
 		return err
 	}
 
+	imageRoot := filepath.Join(rootDirectory, s.GeneratorConfig.ImageRootDirectory)
+
 	verbose("Creating directories in " + rootDirectory)
-	os.Mkdir(generator.CurrentConfig.ImageRootDirectory, 0777)
-	os.Mkdir(path.Join(generator.CurrentConfig.ImageRootDirectory, generator.CurrentConfig.ImageSrcDirectory), 0777)
-	os.Mkdir(path.Join(generator.CurrentConfig.ImageRootDirectory, generator.CurrentConfig.ImageMetaDirectory), 0777)
+	fsys.Mkdir(imageRoot, 0777)
+	fsys.Mkdir(filepath.Join(imageRoot, s.GeneratorConfig.ImageSrcDirectory), 0777)
+	fsys.Mkdir(filepath.Join(imageRoot, s.GeneratorConfig.ImageMetaDirectory), 0777)
 
-	for k := range generator.CurrentConfig.ImageSizes {
-		os.Mkdir(filepath.Join(generator.CurrentConfig.ImageRootDirectory, k), 0777)
+	for k := range s.GeneratorConfig.ImageSizes {
+		fsys.Mkdir(filepath.Join(imageRoot, k), 0777)
 	}
 
 	return nil
@@ -164,16 +170,29 @@ func ConfigureWebFile(source string, dest string, vars interface{}) error {
 }*/
 
 // UpdateWeb takes a folder, and updates the webpages inside of that folder.
+//
+// Deprecated: use Site.UpdateWeb instead.
 func UpdateWeb(folder string) error {
+	return globalSite().UpdateWeb(folder)
+}
+
+// UpdateWeb takes a folder, and updates the webpages inside of that folder
+// using the site's theme.
+func (s *Site) UpdateWeb(folder string) error {
 	verbose("Updating web pages...")
 	f := new(generator.Folder)
 
-	err := f.ReadFolderInfo(path.Join(folder, "folderInfo.json"))
+	err := f.ReadFolderInfo(s.fsys(), filepath.Join(folder, "folderInfo.json"))
 	if checkError(err) {
 		return err
 	}
 
-	err = currentTheme.generateWeb(f.Type, folder, nil)
+	t, err := s.loadTheme()
+	if checkError(err) {
+		return err
+	}
+
+	err = t.generateWeb(s.fsys(), s.GeneratorConfig.WebBaseURL, f.Type, folder, nil)
 	if checkError(err) {
 		return err
 	}
@@ -189,27 +208,37 @@ func UpdateWeb(folder string) error {
 //
 // The page template must have {{.PageVars.PageContent}} in the location of where
 // you want the parsed document to go.
+//
+// Deprecated: use Site.GeneratePage instead.
 func GeneratePage(src string, title string) error {
-	if title == "" {
-		return fmt.Errorf("you need to give the page a filename/title")
-	}
-
 	if CurrentConfig == nil {
 		return fmt.Errorf("you need to use this in conjunction with a valid fotoDen site")
 	}
 
-	f, err := os.Open(src)
+	return globalSite().GeneratePage(src, title)
+}
+
+// GeneratePage generates a page using a markdown document as a source.
+// It will use the 'page' HTML template in the site's theme in order to generate
+// a web page. Takes a source location, and places it at the root of the site.
+//
+// The page template must have {{.PageVars.PageContent}} in the location of where
+// you want the parsed document to go.
+func (s *Site) GeneratePage(src string, title string) error {
+	if title == "" {
+		return fmt.Errorf("you need to give the page a filename/title")
+	}
+
+	err := s.checkSite()
 	if checkError(err) {
 		return err
 	}
 
-	r, err := io.ReadAll(f)
+	r, err := s.fsys().ReadFile(src)
 	if checkError(err) {
 		return err
 	}
 
-	f.Close()
-
 	mdown := goldmark.New(
 		goldmark.WithExtensions(extension.GFM),
 		goldmark.WithRendererOptions(
@@ -228,11 +257,9 @@ func GeneratePage(src string, title string) error {
 		return err
 	}
 
-	if currentTheme == nil {
-		err = openDefaultTheme()
-		if checkError(err) {
-			return err
-		}
+	t, err := s.loadTheme()
+	if checkError(err) {
+		return err
 	}
 
 	v := map[string]string{
@@ -240,16 +267,18 @@ func GeneratePage(src string, title string) error {
 		"title":       title,
 	}
 
-	u, err := url.Parse(generator.CurrentConfig.WebBaseURL)
+	u, err := url.Parse(s.GeneratorConfig.WebBaseURL)
 	if checkError(err) {
 		return err
 	}
 
 	u.Path = path.Join(u.Path, strings.ToLower(strings.ReplaceAll(title, " ", "")))
 
-	err = currentTheme.generateWeb(
+	err = t.generateWeb(
+		s.fsys(),
+		s.GeneratorConfig.WebBaseURL,
 		"page",
-		path.Base(u.Path),
+		filepath.Join(s.RootLocation, path.Base(u.Path)),
 		v,
 	)
 
@@ -258,13 +287,13 @@ func GeneratePage(src string, title string) error {
 	}
 
 	c := new(generator.WebConfig)
-	err = c.ReadWebConfig(filepath.Join(CurrentConfig.RootLocation, "config.json"))
+	err = c.ReadWebConfig(s.fsys(), filepath.Join(s.RootLocation, "config.json"))
 	if checkError(err) {
 		return err
 	}
 
 	c.Pages = append(c.Pages, generator.PageLink{Title: title, Location: u.String()})
-	err = c.WriteWebConfig(filepath.Join(CurrentConfig.RootLocation, "config.json"))
+	err = c.WriteWebConfig(s.fsys(), filepath.Join(s.RootLocation, "config.json"))
 	if checkError(err) {
 		return err
 	}
@@ -36,15 +36,15 @@ func setupWebsite(loc string, theme string) (WebsiteConfig, *generator.WebConfig
 		))
 
 	src := cmdio.ReadInput("Are you going to remotely host your images? If so, type in the URL now, otherwise leave it blank to automatically use local hosting for all images")
-	s := generator.GenerateWebConfig(src)
+	s := w.GeneratorConfig.WebConfig(src)
 	s.WebsiteTitle = w.Name
 	s.Theme = true
 
 	fmt.Println("Here are your current image sizes, for reference:")
-	for k := range generator.CurrentConfig.ImageSizes {
+	for k := range w.GeneratorConfig.ImageSizes {
 		fmt.Println(k)
 	}
-	s.ImageRootDir = generator.CurrentConfig.ImageSrcDirectory
+	s.ImageRootDir = w.GeneratorConfig.ImageSrcDirectory
 	s.ThumbnailFrom = cmdio.ReadInputReq("What size do you want your thumbnails to be? (required)")
 	s.DisplayImageFrom = cmdio.ReadInputReq("What size do you want to display your images as in a fotoDen photo viewer? (required)")
 	s.DownloadSizes = cmdio.ReadInputAsArray("What sizes do you want easily downloadable?", ",")
@@ -81,6 +81,11 @@ func setupConfig() generator.Config {
 				imageSize.MaxWidth, _ = cmdio.ReadInputAsInt("Maximum width of image?")
 				scalePercent, _ := cmdio.ReadInputAsFloat("Image scale percent? [0 - 100%]")
 				imageSize.ScalePercent = scalePercent * 0.1
+				imageSize.Quality, _ = cmdio.ReadInputAsInt("Quality of image? [1 - 100, leave blank for default]")
+				imageSize.Formats = cmdio.ReadInputAsArray("Extra formats to generate next to JPEG? (webp, avif)", ",")
+				if len(imageSize.Formats) > 0 && imageSize.Formats[0] == "" {
+					imageSize.Formats = nil
+				}
 				if imageSize.MaxHeight == 0 && imageSize.MaxWidth == 0 && imageSize.ScalePercent == 0 {
 					fmt.Println("You must set one value to a non-zero value!")
 				} else {
@@ -93,27 +98,27 @@ func setupConfig() generator.Config {
 		config.ImageSizes = generator.DefaultConfig.ImageSizes
 		fmt.Println("Using default image sizes: ")
 		for k, v := range generator.DefaultConfig.ImageSizes {
-			fmt.Printf("Size name: %s, MaxHeight: %d, MaxWidth: %d, ScalePercent: %f\n", k, v.MaxHeight, v.MaxWidth, v.ScalePercent)
+			fmt.Printf("Size name: %s, MaxHeight: %d, MaxWidth: %d, ScalePercent: %f, Formats: %v\n", k, v.MaxHeight, v.MaxWidth, v.ScalePercent, v.Formats)
 		}
 	}
 
 	return config
 }
 
-func setupWebConfig(source string) *generator.WebConfig {
-	config := generator.GenerateWebConfig(source)
+func setupWebConfig(c generator.Config, source string) *generator.WebConfig {
+	config := c.WebConfig(source)
 
 	fmt.Println("Wizard: Setup website config")
 	config.WebsiteTitle = cmdio.ReadInput("What is the title of your website?")
 	config.PhotoURLBase = cmdio.ReadInput("Are you going to be using a remote storage provider for your photos? If so, put in the URL to the folder containing your fotoDen-structured images here.")
 
 	fmt.Println("Here are your current image sizes, for reference:")
-	for k := range generator.CurrentConfig.ImageSizes {
+	for k := range c.ImageSizes {
 		fmt.Println(k)
 	}
 
 	config.Theme = true // TODO: Make selectable themes
-	config.ImageRootDir = generator.CurrentConfig.ImageSrcDirectory
+	config.ImageRootDir = c.ImageSrcDirectory
 	config.ThumbnailFrom = cmdio.ReadInput("What size do you want your thumbnails to be?")
 	config.DisplayImageFrom = cmdio.ReadInput("What size do you want to display your images as in a fotoDen photo viewer?")
 	config.DownloadSizes = cmdio.ReadInputAsArray("What sizes do you want downlodable?", ",")
@@ -121,7 +126,7 @@ func setupWebConfig(source string) *generator.WebConfig {
 	return config
 }
 
-func generateFolderWizard(directory string) (*generator.Folder, error) {
+func generateFolderWizard(fsys generator.FS, directory string) (*generator.Folder, error) {
 	folder, err := generator.GenerateFolderInfo(directory, "")
 	if checkError(err) {
 		return nil, err
@@ -138,7 +143,7 @@ func generateFolderWizard(directory string) (*generator.Folder, error) {
 			fmt.Println("No file detected - ignoring.")
 		} else {
 			folder.Thumbnail = true
-			generator.MakeFolderThumbnail(thumb, directory)
+			generator.MakeFolderThumbnail(fsys, thumb, directory)
 		}
 	}
 